@@ -0,0 +1,8 @@
+//go:build !windows
+
+package tatter
+
+// Alternate data streams only exist on NTFS, so there is nothing to list.
+func alternateStreams(path string) ([]string, error) {
+	return nil, nil
+}
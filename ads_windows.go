@@ -0,0 +1,59 @@
+//go:build windows
+
+package tatter
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	kernel32             = syscall.NewLazyDLL("kernel32.dll")
+	procFindFirstStreamW = kernel32.NewProc("FindFirstStreamW")
+	procFindNextStreamW  = kernel32.NewProc("FindNextStreamW")
+)
+
+const errorHandleEOF syscall.Errno = 38
+
+// WIN32_FIND_STREAM_DATA, as filled by FindFirstStreamW/FindNextStreamW.
+type win32FindStreamData struct {
+	streamSize int64
+	streamName [syscall.MAX_PATH + 36]uint16
+}
+
+// Lists the alternate data streams of the file at path, as suffixes
+// (":name") that can be appended to path to open them.
+// Streams are enumerated with FindFirstStreamW and FindNextStreamW using
+// the FindStreamInfoStandard level, which yields names in the form
+// ":name:$DATA". The unnamed main stream ("::$DATA") is skipped, since
+// it is the file content shreded as usual. Filesystems without streams
+// report ERROR_HANDLE_EOF straight away, which is not an error.
+func alternateStreams(path string) ([]string, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return nil, err
+	}
+	var data win32FindStreamData
+	h, _, err := procFindFirstStreamW.Call(uintptr(unsafe.Pointer(p)), 0, uintptr(unsafe.Pointer(&data)), 0)
+	if syscall.Handle(h) == syscall.InvalidHandle {
+		if err == errorHandleEOF {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer syscall.FindClose(syscall.Handle(h))
+	var streams []string
+	for {
+		name := syscall.UTF16ToString(data.streamName[:])
+		if name != "::$DATA" {
+			streams = append(streams, strings.TrimSuffix(name, ":$DATA"))
+		}
+		if ok, _, err := procFindNextStreamW.Call(h, uintptr(unsafe.Pointer(&data))); ok == 0 {
+			if err == errorHandleEOF {
+				return streams, nil
+			}
+			return streams, err
+		}
+	}
+}
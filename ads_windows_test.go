@@ -0,0 +1,31 @@
+//go:build windows
+
+package tatter
+
+import (
+	"os"
+	"testing"
+)
+
+func TestAlternateStreams(t *testing.T) {
+	path := "testdata/test/ads.bin"
+	f, err := copyFile(t, "testdata/small.bin", path)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove(path)
+	if err := os.WriteFile(path+":hidden", []byte("Hidden123"), 0644); err != nil {
+		t.Skipf("filesystem without alternate data streams: %v\n", err)
+	}
+	streams, err := alternateStreams(path)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if len(streams) != 1 || streams[0] != ":hidden" {
+		t.Fatalf("expected [:hidden], got %v\n", streams)
+	}
+	if err := Shred(path); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+}
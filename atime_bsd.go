@@ -0,0 +1,19 @@
+//go:build darwin || freebsd
+
+package tatter
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Returns the last access time of the file described by info, or its
+// modification time if it is not known.
+func accessTime(info os.FileInfo) time.Time {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return info.ModTime()
+	}
+	return time.Unix(st.Atimespec.Unix())
+}
@@ -0,0 +1,14 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package tatter
+
+import (
+	"os"
+	"time"
+)
+
+// Returns the modification time of the file described by info, since
+// its last access time cannot be queried on this platform.
+func accessTime(info os.FileInfo) time.Time {
+	return info.ModTime()
+}
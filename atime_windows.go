@@ -0,0 +1,17 @@
+package tatter
+
+import (
+	"os"
+	"syscall"
+	"time"
+)
+
+// Returns the last access time of the file described by info, or its
+// modification time if it is not known.
+func accessTime(info os.FileInfo) time.Time {
+	d, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return info.ModTime()
+	}
+	return time.Unix(0, d.LastAccessTime.Nanoseconds())
+}
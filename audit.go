@@ -0,0 +1,157 @@
+package tatter
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Longest audit record read back to chain the next one to it.
+const maxAuditLine = 64 << 10
+
+// Serializes the appends of this process, which also locks the file
+// against other processes.
+var auditMu sync.Mutex
+
+// A record of the audit log of WithAuditLog, written as a line of JSON
+// with its fields in this order. Prev chains it to the line before it:
+// it holds the SHA-256, in hex, of the bytes of that line without its
+// newline, or 64 zeros for the first record. Modifying, inserting or
+// removing a line then breaks the chain at the next one, which
+// VerifyAuditLog detects. Removing lines at the end is not detected,
+// unless the hash of the last line is kept somewhere else.
+type AuditRecord struct {
+	Time   time.Time `json:"time"`            // when the shred ended, in UTC
+	Path   string    `json:"path"`            // as given to shred it
+	Size   int64     `json:"size"`            // of the file, in bytes
+	Passes int       `json:"passes"`          // completed
+	Bytes  int64     `json:"bytes"`           // overwritten by all passes
+	Error  string    `json:"error,omitempty"` // why it failed, if it did
+	Prev   string    `json:"prev"`            // hash of the previous line
+}
+
+// Returns the hash chaining a record to the given line, or to nothing
+// if it is nil.
+func chainHash(line []byte) string {
+	if line == nil {
+		return strings.Repeat("0", 2*sha256.Size)
+	}
+	sum := sha256.Sum256(line)
+	return hex.EncodeToString(sum[:])
+}
+
+// Returns the last line of f, without its newline, or nil if f is
+// empty.
+func lastLine(f *os.File) ([]byte, error) {
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	size := info.Size()
+	if size == 0 {
+		return nil, nil
+	}
+	n := size
+	if n > maxAuditLine {
+		n = maxAuditLine
+	}
+	b := make([]byte, n)
+	if _, err := f.ReadAt(b, size-n); err != nil {
+		return nil, err
+	}
+	b = bytes.TrimSuffix(b, []byte("\n"))
+	i := bytes.LastIndexByte(b, '\n')
+	if i < 0 && n < size {
+		return nil, errors.New("last audit record is too long")
+	}
+	return b[i+1:], nil
+}
+
+// Appends a record of a shred that ended with err to the audit log at
+// path, chained to its last line, and syncs it.
+func appendAudit(path string, stats ShredStats, err error) error {
+	auditMu.Lock()
+	defer auditMu.Unlock()
+	f, ferr := os.OpenFile(path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if ferr != nil {
+		return ferr
+	}
+	defer f.Close()
+	for i := 0; ; i++ { // other processes hold the lock briefly
+		ferr = lockFile(f)
+		if !errors.Is(ferr, ErrFileLocked) || i == 100 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if ferr != nil {
+		return ferr
+	}
+	last, ferr := lastLine(f)
+	if ferr != nil {
+		return ferr
+	}
+	rec := AuditRecord{
+		Time:   now().UTC(),
+		Path:   stats.Path,
+		Size:   stats.Size,
+		Passes: stats.Passes,
+		Bytes:  stats.BytesOverwritten,
+		Prev:   chainHash(last),
+	}
+	if err != nil {
+		rec.Error = err.Error()
+	}
+	b, ferr := json.Marshal(rec)
+	if ferr != nil {
+		return ferr
+	}
+	if _, ferr = f.Write(append(b, '\n')); ferr != nil {
+		return ferr
+	}
+	return f.Sync()
+}
+
+// Checks the hash chain of the audit log at path, returning
+// ErrAuditTampered with the number of the first line, counting from 1,
+// that does not follow the line before it.
+func VerifyAuditLog(path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	s := bufio.NewScanner(f)
+	s.Buffer(make([]byte, 4096), maxAuditLine)
+	var prev []byte
+	for n := 1; s.Scan(); n++ {
+		var rec AuditRecord
+		if err := json.Unmarshal(s.Bytes(), &rec); err != nil {
+			return fmt.Errorf("%w: line %d: %v", ErrAuditTampered, n, err)
+		}
+		if rec.Prev != chainHash(prev) {
+			return fmt.Errorf("%w: line %d", ErrAuditTampered, n)
+		}
+		prev = append(prev[:0], s.Bytes()...)
+	}
+	return s.Err()
+}
+
+// Refuses to shred the audit log of the options, if path is it.
+func (c *config) checkAuditLog(path string, info os.FileInfo) error {
+	if c.auditLog == "" {
+		return nil
+	}
+	if log, err := os.Stat(c.auditLog); err == nil && os.SameFile(info, log) {
+		return fmt.Errorf("%s is the audit log, which is never shreded", path)
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+package tatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestWithAuditLog(t *testing.T) {
+	const log = "testdata/test/audit.log"
+	defer os.Remove(log)
+	paths := []string{"testdata/test/missing.bin"}
+	for i := 0; i < 3; i++ {
+		path := fmt.Sprintf("testdata/test/audit%d.bin", i)
+		f, err := copyFile(t, "testdata/large.bin", path)
+		if err != nil {
+			t.Fatalf("err: %v\n", err)
+		}
+		f.Close()
+		defer os.Remove(path)
+		paths = append(paths, path)
+	}
+	if _, err := ShredAll(paths, WithAuditLog(log)); err == nil {
+		t.Fatalf("expected the missing file err, got nil\n")
+	}
+	if err := Shred(log, WithAuditLog(log)); err == nil || !strings.Contains(err.Error(), "audit log") {
+		t.Fatalf("expected the audit log refused, got %v\n", err)
+	}
+	if err := VerifyAuditLog(log); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	b, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	lines := bytes.Split(bytes.TrimSuffix(b, []byte("\n")), []byte("\n"))
+	if len(lines) != 5 {
+		t.Fatalf("expected 5 records, got %d\n", len(lines))
+	}
+	failed := 0
+	for _, line := range lines {
+		var rec AuditRecord
+		if err := json.Unmarshal(line, &rec); err != nil {
+			t.Fatalf("err: %v\n", err)
+		}
+		if rec.Error != "" {
+			failed++
+		} else if rec.Size != 3150 || rec.Passes != 3 || rec.Bytes != 3*3150 {
+			t.Fatalf("unexpected record %s\n", line)
+		}
+	}
+	if failed != 2 {
+		t.Fatalf("expected 2 failed records, got %d\n", failed)
+	}
+	// Editing a record breaks the chain at the next one.
+	tampered := bytes.Replace(b, []byte(`"passes":3`), []byte(`"passes":4`), 1)
+	if err := os.WriteFile(log, tampered, 0600); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := VerifyAuditLog(log); !errors.Is(err, ErrAuditTampered) {
+		t.Fatalf("expected ErrAuditTampered, got %v\n", err)
+	}
+}
@@ -0,0 +1,123 @@
+package tatter
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+)
+
+const hashBlock int64 = 64 << 10 // bytes hashed together by WithChangedBlocksOnly
+
+// Hashes of the blocks of a file, as saved in the sidecar file of
+// WithChangedBlocksOnly.
+type blockHashes struct {
+	BlockSize int64
+	Hashes    [][]byte
+}
+
+// Hashes every hashBlock bytes of the first size bytes of f.
+func hashBlocks(f io.ReaderAt, size int64) ([][]byte, error) {
+	var hashes [][]byte
+	b := make([]byte, hashBlock)
+	for off := int64(0); off < size; off += hashBlock {
+		n := hashBlock
+		if off+n > size {
+			n = size - off
+		}
+		if _, err := f.ReadAt(b[:n], off); err != nil && err != io.EOF {
+			return nil, err
+		}
+		sum := sha256.Sum256(b[:n])
+		hashes = append(hashes, sum[:])
+	}
+	return hashes, nil
+}
+
+// Loads the block hashes saved at path, or returns nil if there are
+// none, or they were saved with another block size.
+func loadBlockHashes(path string) (*blockHashes, error) {
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var h blockHashes
+	if err := json.Unmarshal(b, &h); err != nil {
+		return nil, err
+	}
+	if h.BlockSize != hashBlock {
+		return nil, nil
+	}
+	return &h, nil
+}
+
+// Saves the hashes of the blocks f holds now at path, through a
+// temporary file renamed over the previous one, so a crash leaves
+// either of them whole.
+func saveBlockHashes(path string, f File) error {
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	h := blockHashes{BlockSize: hashBlock}
+	if h.Hashes, err = hashBlocks(f, info.Size()); err != nil {
+		return err
+	}
+	b, err := json.Marshal(h)
+	if err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// Same as shredFile, but once the sidecar file of WithChangedBlocksOnly
+// holds the hashes of a previous run, only the blocks whose hash
+// changed, or that were not there, are overwritten.
+func shredChanged(ctx context.Context, f File, c *config, stats *ShredStats) error {
+	old, err := loadBlockHashes(c.blockHashes)
+	if err != nil {
+		return err
+	}
+	if old == nil {
+		return shredFile(ctx, f, c, stats)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	size, err := c.extent(f, info)
+	if err != nil {
+		return err
+	}
+	hashes, err := hashBlocks(f, size)
+	if err != nil {
+		return err
+	}
+	var ranges [][2]int64
+	for i, h := range hashes {
+		if i < len(old.Hashes) && bytes.Equal(h, old.Hashes[i]) {
+			continue
+		}
+		off := int64(i) * hashBlock
+		end := off + hashBlock
+		if end > size {
+			end = size
+		}
+		ranges = append(ranges, [2]int64{off, end})
+	}
+	if ranges, err = mergeRanges(ranges, size); err != nil {
+		return err
+	}
+	return shredRanges(ctx, f, ranges, c, stats)
+}
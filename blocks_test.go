@@ -0,0 +1,64 @@
+package tatter
+
+import (
+	"os"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type TestChangedBlocksTable struct {
+	name   string
+	change func(f *os.File) error
+	want   int64
+}
+
+func TestWithChangedBlocksOnly(t *testing.T) {
+	const size = 3*hashBlock + 3392
+	if err := os.WriteFile("testdata/test/blocks.bin", make([]byte, size), 0644); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/blocks.bin")
+	defer os.Remove("testdata/test/blocks.json")
+	var tests = []TestChangedBlocksTable{
+		{"First", nil, size},
+		{"Unchanged", nil, 0},
+		{"Changed", func(f *os.File) error {
+			_, err := f.WriteAt([]byte("new secret"), hashBlock+10)
+			return err
+		}, hashBlock},
+		{"Appended", func(f *os.File) error {
+			_, err := f.WriteAt(make([]byte, 1000), size)
+			return err
+		}, 3392 + 1000},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.change != nil {
+				f, err := os.OpenFile("testdata/test/blocks.bin", os.O_RDWR, 0)
+				if err != nil {
+					t.Fatalf("err: %v\n", err)
+				}
+				err = tt.change(f)
+				f.Close()
+				if err != nil {
+					t.Fatalf("err: %v\n", err)
+				}
+			}
+			var written atomic.Int64
+			trace := func(off, n int64, d time.Duration) { written.Add(n) }
+			if err := Scrub("testdata/test/blocks.bin", WithStandard(StandardQuick), WithChangedBlocksOnly("testdata/test/blocks.json"), WithWriteTrace(trace)); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			if written.Load() != tt.want {
+				t.Fatalf("expected %d bytes overwritten, got %d\n", tt.want, written.Load())
+			}
+		})
+	}
+}
+
+func TestWithChangedBlocksOnlyNeedsScrub(t *testing.T) {
+	if err := Shred("testdata/test/nonexistent", WithChangedBlocksOnly("testdata/test/blocks.json")); err == nil {
+		t.Fatalf("expected Shred to refuse changed blocks\n")
+	}
+}
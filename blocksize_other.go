@@ -0,0 +1,9 @@
+//go:build !unix
+
+package tatter
+
+// The block size is not queried on this platform, so the most common
+// physical sector size is assumed.
+func blockSize(f File) int64 {
+	return defBlock
+}
@@ -0,0 +1,23 @@
+//go:build unix
+
+package tatter
+
+import (
+	"os"
+	"syscall"
+)
+
+// Returns the block size the filesystem prefers for I/O on the open
+// file f, or defBlock if it cannot be queried, as for files not of the
+// os package.
+func blockSize(f File) int64 {
+	osf, ok := f.(*os.File)
+	if !ok {
+		return defBlock
+	}
+	var st syscall.Stat_t
+	if err := syscall.Fstat(int(osf.Fd()), &st); err != nil || st.Blksize <= 0 {
+		return defBlock
+	}
+	return int64(st.Blksize)
+}
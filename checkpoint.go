@@ -0,0 +1,140 @@
+package tatter
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sort"
+	"sync"
+	"time"
+)
+
+// How often the checkpoint is saved while a pass is running.
+const checkpointEvery = time.Second
+
+// Progress of a shred saved to a checkpoint file.
+type checkpointState struct {
+	Config  string  `json:"config"`  // hash of what the passes depend on
+	Pass    int     `json:"pass"`    // pass in progress
+	Offsets []int64 `json:"offsets"` // how far each partition of the pass got
+}
+
+// Keeps the checkpoint file of a shred up to date.
+type checkpointer struct {
+	path   string
+	f      File
+	bounds []int64
+	mu     sync.Mutex
+	state  checkpointState
+	saved  time.Time
+	err    error
+}
+
+// Returns a hash of the options and sizes the passes of a shred depend
+// on, so that a checkpoint is only resumed by the same shred. The XOR
+// pass counts, since it comes first and shifts the index of the others.
+func checkpointHash(c *config, size, bufSize int64) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%d %d %d %v %v\n", size, bufSize, c.nthreads(), c.transform != nil, c.xorPass)
+	for _, src := range c.sources {
+		switch src.(type) {
+		case ConstantSource, PatternSource, CounterSource:
+			fmt.Fprintf(h, "%T %v\n", src, src)
+		default:
+			fmt.Fprintf(h, "%T\n", src)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// Loads the checkpoint at path for the shred of f, or starts a new one
+// if there is none. Returns ErrCheckpointMismatch if it was saved by a
+// shred with different options or of a file of a different size.
+func loadCheckpoint(path string, f File, c *config, size, bufSize int64) (*checkpointer, error) {
+	cp := &checkpointer{path: path, f: f, bounds: partition(size, bufSize, c.nthreads()), saved: now()}
+	cp.state.Config = checkpointHash(c, size, bufSize)
+	b, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cp, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var state checkpointState
+	if err := json.Unmarshal(b, &state); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrCheckpointMismatch, err)
+	}
+	if state.Config != cp.state.Config {
+		return nil, fmt.Errorf("%w: %s was saved with other options or file size", ErrCheckpointMismatch, path)
+	}
+	if state.Offsets != nil && len(state.Offsets) != len(cp.bounds)-1 {
+		return nil, fmt.Errorf("%w: %s has %d partitions, want %d", ErrCheckpointMismatch, path, len(state.Offsets), len(cp.bounds)-1)
+	}
+	cp.state = state
+	return cp, nil
+}
+
+// Returns a progress function that records every write in the
+// checkpoint, saving it every checkpointEvery, before calling next, if
+// not nil.
+func (cp *checkpointer) advance(next func(pass int, off, n int64)) func(pass int, off, n int64) {
+	return func(pass int, off, n int64) {
+		cp.mu.Lock()
+		if cp.state.Offsets == nil {
+			cp.state.Offsets = append([]int64(nil), cp.bounds[:len(cp.bounds)-1]...)
+		}
+		i := sort.Search(len(cp.bounds), func(i int) bool { return cp.bounds[i] > off }) - 1
+		cp.state.Offsets[i] = off + n
+		if now().Sub(cp.saved) >= checkpointEvery && cp.err == nil {
+			cp.err = cp.save()
+		}
+		cp.mu.Unlock()
+		if next != nil {
+			next(pass, off, n)
+		}
+	}
+}
+
+// Records the end of the given pass, completed or not, and saves the
+// checkpoint, or removes it once the last pass is completed. Returns
+// the first error found saving it during the pass, if any.
+func (cp *checkpointer) finish(pass int, completed bool, passes int) error {
+	cp.mu.Lock()
+	defer cp.mu.Unlock()
+	if cp.err != nil {
+		return cp.err
+	}
+	if !completed {
+		return cp.save()
+	}
+	if pass == passes-1 {
+		return os.Remove(cp.path)
+	}
+	cp.state.Pass = pass + 1
+	cp.state.Offsets = nil
+	return cp.save()
+}
+
+// Saves the checkpoint, after syncing the file so that it never claims
+// more than what reached the storage. The checkpoint is written to a
+// temporary file and renamed over the previous one, so a crash leaves
+// either of them whole.
+func (cp *checkpointer) save() error {
+	cp.saved = now()
+	if err := cp.f.Sync(); err != nil {
+		return err
+	}
+	b, err := json.Marshal(cp.state)
+	if err != nil {
+		return err
+	}
+	tmp := cp.path + ".tmp"
+	if err := os.WriteFile(tmp, b, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, cp.path)
+}
@@ -0,0 +1,165 @@
+package tatter
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"sync"
+	"testing"
+	"testing/iotest"
+)
+
+// Writes a checkpoint for a shred of size bytes with the given options.
+func writeCheckpoint(t *testing.T, path string, opts []Option, size int64, state checkpointState) {
+	t.Helper()
+	c := newConfig(opts)
+	state.Config = checkpointHash(c, size, c.bufSize(size))
+	b, err := json.Marshal(state)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := os.WriteFile(path, b, 0o600); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+}
+
+func TestCheckpointResume(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/resume.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/resume.bin")
+	const cp = "testdata/test/resume.checkpoint"
+	defer os.Remove(cp)
+	opts := []Option{WithPassSources(ConstantSource(1), ConstantSource(2), ConstantSource(3))}
+	c := newConfig(opts)
+	bufSize := c.bufSize(40716)
+	bounds := partition(40716, bufSize, threads)
+	// The last pass got one buffer into the first partition, and
+	// completed the second one.
+	from := []int64{bufSize, bounds[2], bounds[2]}
+	writeCheckpoint(t, cp, opts, 40716, checkpointState{Pass: 2, Offsets: from})
+	c.keep = true
+	c.checkpoint = cp
+	stats, err := shred(context.Background(), "testdata/test/resume.bin", c)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if stats.Passes != 3 {
+		t.Fatalf("expected 3 passes, got %d\n", stats.Passes)
+	}
+	if want := 40716 - bufSize - (bounds[2] - bounds[1]); stats.BytesOverwritten != want {
+		t.Fatalf("expected %d bytes overwritten, got %d\n", want, stats.BytesOverwritten)
+	}
+	orig, err := os.ReadFile("testdata/extra.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	b, err := os.ReadFile("testdata/test/resume.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	for i, v := range b {
+		want := byte(3)
+		if int64(i) < bufSize || (int64(i) >= bounds[1] && int64(i) < bounds[2]) {
+			want = orig[i]
+		}
+		if v != want {
+			t.Fatalf("byte %d is %#x, want %#x\n", i, v, want)
+		}
+	}
+	if _, err := os.Stat(cp); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected the checkpoint removed, got %v\n", err)
+	}
+}
+
+type TestCheckpointMismatchTable struct {
+	name        string
+	saved, opts []Option
+}
+
+func TestCheckpointMismatch(t *testing.T) {
+	var tests = []TestCheckpointMismatchTable{
+		{"Standard", []Option{WithStandard(StandardDoD3)}, []Option{WithStandard(StandardDoD7)}},
+		{"XorPass", []Option{WithStandard(StandardDoD3)}, []Option{WithStandard(StandardDoD3), WithXorPass(true)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := copyFile(t, "testdata/extra.bin", "testdata/test/mismatch.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			f.Close()
+			defer os.Remove("testdata/test/mismatch.bin")
+			const cp = "testdata/test/mismatch.checkpoint"
+			defer os.Remove(cp)
+			writeCheckpoint(t, cp, tt.saved, 40716, checkpointState{Pass: 1})
+			err = Shred("testdata/test/mismatch.bin", append(tt.opts, WithCheckpoint(cp))...)
+			if !errors.Is(err, ErrCheckpointMismatch) {
+				t.Fatalf("expected ErrCheckpointMismatch, got %v\n", err)
+			}
+			b, err := os.ReadFile("testdata/test/mismatch.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			orig, _ := os.ReadFile("testdata/extra.bin")
+			if !bytes.Equal(b, orig) {
+				t.Fatalf("expected the file untouched on a mismatch\n")
+			}
+		})
+	}
+}
+
+// Serializes the reads of the threads of a pass from a reader.
+type lockedReader struct {
+	mu sync.Mutex
+	r  io.Reader
+}
+
+func (r *lockedReader) Read(b []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.r.Read(b)
+}
+
+func TestCheckpointInterrupted(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/interrupted.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/interrupted.bin")
+	const cp = "testdata/test/interrupted.checkpoint"
+	defer os.Remove(cp)
+	// The second pass fails once 20000 bytes of it were read.
+	failing := RandomSource{&lockedReader{r: io.MultiReader(io.LimitReader(rand.Reader, 20000), iotest.ErrReader(errors.New("Rand err")))}}
+	err = Shred("testdata/test/interrupted.bin", WithPassSources(RandomSource{}, failing, RandomSource{}), WithCheckpoint(cp))
+	var e *ShredError
+	if !errors.As(err, &e) || e.Pass != 1 {
+		t.Fatalf("expected a *ShredError on pass 1, got %v\n", err)
+	}
+	b, err := os.ReadFile(cp)
+	if err != nil {
+		t.Fatalf("expected a checkpoint, got %v\n", err)
+	}
+	var state checkpointState
+	if err := json.Unmarshal(b, &state); err != nil || state.Pass != 1 {
+		t.Fatalf("expected a checkpoint on pass 1, got %s: %v\n", b, err)
+	}
+	stats, err := ShredWithStats("testdata/test/interrupted.bin", WithCheckpoint(cp))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if stats.Passes != 3 || stats.BytesOverwritten >= 2*40716 {
+		t.Fatalf("expected the shred resumed on pass 1, got %+v\n", stats)
+	}
+	if _, err := os.Stat(cp); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected the checkpoint removed, got %v\n", err)
+	}
+}
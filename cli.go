@@ -0,0 +1,82 @@
+package tatter
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// Per-file entry of the --json output.
+type jsonResult struct {
+	Path       string `json:"path"`
+	Bytes      int64  `json:"bytes"`
+	Passes     int    `json:"passes"`
+	DurationMS int64  `json:"duration_ms"`
+	Error      string `json:"error"`
+}
+
+// Runs the tatter command line with the given arguments (without the
+// program name), writing results to stdout and failures to stderr.
+// Returns the exit code: 0 if every file was shreded, 1 if any of them
+// failed and 2 on usage errors.
+func RunCLI(args []string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("tatter", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	asJSON := fs.Bool("json", false, "print a JSON array of per-file results")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "usage: tatter [--json] file...")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	paths := fs.Args()
+	if len(paths) == 0 {
+		fs.Usage()
+		return 2
+	}
+	results, err := ShredAll(paths)
+	code := 0
+	if err != nil {
+		code = 1
+	}
+	order := make([]string, 0, len(results))
+	seen := make(map[string]bool, len(results))
+	for _, path := range paths {
+		if !seen[path] {
+			seen[path] = true
+			order = append(order, path)
+		}
+	}
+	if *asJSON {
+		out := make([]jsonResult, 0, len(order))
+		for _, path := range order {
+			r := results[path]
+			jr := jsonResult{
+				Path:       path,
+				Bytes:      r.Stats.BytesOverwritten,
+				Passes:     r.Stats.Passes,
+				DurationMS: r.Stats.Duration.Milliseconds(),
+			}
+			if r.Err != nil {
+				jr.Error = r.Err.Error()
+			}
+			out = append(out, jr)
+		}
+		enc := json.NewEncoder(stdout)
+		if err := enc.Encode(out); err != nil {
+			fmt.Fprintln(stderr, err)
+			return 1
+		}
+		return code
+	}
+	for _, path := range order {
+		if r := results[path]; r.Err != nil {
+			fmt.Fprintf(stderr, "%s: %v\n", path, r.Err)
+		} else {
+			fmt.Fprintf(stdout, "%s: shreded %d bytes in %d passes\n", path, r.Stats.BytesOverwritten, r.Stats.Passes)
+		}
+	}
+	return code
+}
@@ -0,0 +1,73 @@
+package tatter
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"testing"
+)
+
+type TestCLITable struct {
+	name  string
+	args  []string
+	files []string
+	want  int
+}
+
+func TestRunCLI(t *testing.T) {
+	var tests = []TestCLITable{
+		{"NoArgs", []string{}, nil, 2},
+		{"BadFlag", []string{"--nope"}, nil, 2},
+		{"Single", []string{"testdata/test/cli1.bin"}, []string{"cli1.bin"}, 0},
+		{"Missing", []string{"testdata/test/cli1.bin", "testdata/test/nonexistent"}, []string{"cli1.bin"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, file := range tt.files {
+				f, err := copyFile(t, "testdata/small.bin", "testdata/test/"+file)
+				if err != nil {
+					t.Fatalf("err: %v\n", err)
+				}
+				f.Close()
+			}
+			var stdout, stderr bytes.Buffer
+			if code := RunCLI(tt.args, &stdout, &stderr); code != tt.want {
+				t.Fatalf("got exit code %d, want %d\n", code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRunCLIJSON(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/clijson.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	var stdout, stderr bytes.Buffer
+	args := []string{"--json", "testdata/test/clijson.bin", "testdata/test/nonexistent"}
+	if code := RunCLI(args, &stdout, &stderr); code != 1 {
+		t.Fatalf("got exit code %d, want 1\n", code)
+	}
+	var out []map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &out); err != nil {
+		t.Fatalf("invalid json %q: %v\n", stdout.String(), err)
+	}
+	if len(out) != 2 {
+		t.Fatalf("got %d results, want 2\n", len(out))
+	}
+	for _, key := range []string{"path", "bytes", "passes", "duration_ms", "error"} {
+		if _, ok := out[1][key]; !ok {
+			t.Fatalf("key %s missing in %v\n", key, out[1])
+		}
+	}
+	if out[0]["error"] != "" || out[0]["bytes"] != float64(3150*3) {
+		t.Fatalf("unexpected result %v\n", out[0])
+	}
+	if out[1]["error"] == "" {
+		t.Fatalf("expected error for %v\n", out[1]["path"])
+	}
+	if _, err := os.Stat("testdata/test/clijson.bin"); err == nil {
+		t.Fatalf("file has not been removed\n")
+	}
+}
@@ -0,0 +1,12 @@
+// Command tatter shreds the files given as arguments.
+package main
+
+import (
+	"os"
+
+	"github.com/raulojeda22/tatter"
+)
+
+func main() {
+	os.Exit(tatter.RunCLI(os.Args[1:], os.Stdout, os.Stderr))
+}
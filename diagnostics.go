@@ -0,0 +1,76 @@
+package tatter
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+)
+
+const (
+	sampleCount = 32 // offsets sampled by pass diagnostics
+	sampleLen   = 8  // bytes read back at every sampled offset
+)
+
+// Records the data written at a few offsets evenly spread over a file,
+// so that it can be read back once the pass is synced. Every sample has
+// its own buffer, and concurrent writers only touch disjoint bytes.
+type sampler struct {
+	offsets []int64
+	data    [][]byte
+}
+
+// Returns a sampler for a file of the given size.
+func newSampler(size int64) *sampler {
+	s := &sampler{}
+	for i := int64(0); i < sampleCount; i++ {
+		off := i * size / sampleCount
+		if n := len(s.offsets); n > 0 && off < s.offsets[n-1]+sampleLen {
+			continue
+		}
+		l := size - off
+		if l <= 0 {
+			break
+		}
+		if l > sampleLen {
+			l = sampleLen
+		}
+		s.offsets = append(s.offsets, off)
+		s.data = append(s.data, make([]byte, l))
+	}
+	return s
+}
+
+func (s *sampler) record(b []byte, off int64) {
+	end := off + int64(len(b))
+	for i, so := range s.offsets {
+		lo, hi := so, so+int64(len(s.data[i]))
+		if off > lo {
+			lo = off
+		}
+		if end < hi {
+			hi = end
+		}
+		if lo < hi {
+			copy(s.data[i][lo-so:hi-so], b[lo-off:hi-off])
+		}
+	}
+}
+
+// Syncs the file and reads back every sample, returning
+// ErrPassNotPersisted if any of them differs from what the given pass
+// wrote.
+func (s *sampler) check(f File, pass int) error {
+	if err := f.Sync(); err != nil {
+		return err
+	}
+	for i, off := range s.offsets {
+		got := make([]byte, len(s.data[i]))
+		if _, err := f.ReadAt(got, off); err != nil && err != io.EOF {
+			return err
+		}
+		if !bytes.Equal(got, s.data[i]) {
+			return fmt.Errorf("%w: pass %d wrote %x at offset %d, read back %x", ErrPassNotPersisted, pass, s.data[i], off, got)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package tatter
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+type TestNewSamplerTable struct {
+	name    string
+	size    int64
+	samples int
+	last    int64
+}
+
+func TestNewSampler(t *testing.T) {
+	var tests = []TestNewSamplerTable{
+		{"Empty", 0, 0, -1},
+		{"Tiny", 5, 1, 0},
+		{"Overlapping", 64, 8, 56},
+		{"Small", 1000, 32, 968},
+		{"Extra", 40716, 32, 39443},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := newSampler(tt.size)
+			if len(s.offsets) != tt.samples {
+				t.Fatalf("expected %d samples, got %d\n", tt.samples, len(s.offsets))
+			}
+			if tt.samples > 0 && s.offsets[tt.samples-1] != tt.last {
+				t.Fatalf("expected last sample at %d, got %d\n", tt.last, s.offsets[tt.samples-1])
+			}
+		})
+	}
+}
+
+func TestSamplerRecord(t *testing.T) {
+	s := newSampler(64)
+	b := make([]byte, 64)
+	for i := range b {
+		b[i] = byte(i)
+	}
+	// Record in uneven batches, splitting samples between them.
+	for _, r := range [][2]int{{0, 5}, {5, 21}, {21, 64}} {
+		s.record(b[r[0]:r[1]], int64(r[0]))
+	}
+	for i, off := range s.offsets {
+		for j, v := range s.data[i] {
+			if v != byte(off)+byte(j) {
+				t.Fatalf("sample at %d byte %d is %d, want %d\n", off, j, v, byte(off)+byte(j))
+			}
+		}
+	}
+}
+
+func TestSamplerCheck(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/diagnostics.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/diagnostics.bin")
+	defer f.Close()
+	b, err := os.ReadFile("testdata/extra.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	s := newSampler(int64(len(b)))
+	s.record(b, 0)
+	if err := s.check(f, 0); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	// A write the pass did not make, as if it had been dropped.
+	s.data[3][0]++
+	if err := s.check(f, 2); !errors.Is(err, ErrPassNotPersisted) {
+		t.Fatalf("err: %v, want ErrPassNotPersisted\n", err)
+	}
+}
+
+func TestShredPassDiagnostics(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/diagnostics2.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	stats, err := ShredWithStats("testdata/test/diagnostics2.bin", WithPassDiagnostics(true), WithParanoidVerify(true))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if stats.Passes != 3 {
+		t.Fatalf("passes: %d, want 3\n", stats.Passes)
+	}
+}
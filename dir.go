@@ -0,0 +1,202 @@
+package tatter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Well known files created by operating systems and file managers,
+// skipped along with hidden files by WithSkipHidden.
+var systemFiles = map[string]bool{
+	".DS_Store":    true,
+	".localized":   true,
+	"Thumbs.db":    true,
+	"ehthumbs.db":  true,
+	"desktop.ini":  true,
+	"Desktop.ini":  true,
+	"$RECYCLE.BIN": true,
+}
+
+// Reports whether the walked entry is hidden or a known system file.
+// Names starting with a dot are hidden everywhere, and on Windows so are
+// entries with the hidden or system attribute.
+func isHidden(name string, d fs.DirEntry) bool {
+	if strings.HasPrefix(name, ".") || systemFiles[name] {
+		return true
+	}
+	info, err := d.Info()
+	return err == nil && hiddenAttr(info)
+}
+
+// Identifies a file by its device and inode, or by its absolute path
+// where those are not available.
+type fileKey struct {
+	dev, ino uint64
+	path     string
+}
+
+// Returns path relative to root, and whether it lies within root.
+func within(path, root string) (string, bool) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", false
+	}
+	absRoot, err := filepath.Abs(root)
+	if err != nil {
+		return "", false
+	}
+	rel, err := filepath.Rel(absRoot, absPath)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return rel, true
+}
+
+// Reports whether the walked entry was modified after the given time.
+// Entries whose time cannot be read are taken as recent, to be safe.
+func recent(d fs.DirEntry, after time.Time) bool {
+	info, err := d.Info()
+	return err != nil || info.ModTime().After(after)
+}
+
+// Shreds every regular file under root with a pool of const workers
+// goroutines, checking first that the descriptor limit of the process
+// leaves room for all of them, or returning ErrInsufficientFDs, and
+// then removes the directories left empty, root included. Symlinks and
+// other non regular files are not followed nor touched, they are
+// reported as skipped instead, so the directories holding them are
+// kept. With WithFollowSymlinks, symlinks are still kept, but the files
+// they lead to are shreded too, and the directories they lead to within
+// root are removed once empty. With WithFileSystem, the tree is walked
+// and removed through it. Returns the outcome of every file and skipped
+// entry keyed by its path, and the first error found, if any.
+func ShredDir(root string, opts ...Option) (map[string]Result, error) {
+	return ShredDirContext(context.Background(), root, opts...)
+}
+
+// Same as ShredDir, but stops promptly once ctx is done: the walk ends,
+// no more files are dispatched to the workers, and the files in flight
+// abort between batches, left partially overwritten and not removed.
+// Returns ctx.Err() along with the results so far, where files that
+// were never started are reported as skipped and no directory is
+// removed.
+func ShredDirContext(ctx context.Context, root string, opts ...Option) (results map[string]Result, err error) {
+	c := newConfig(opts)
+	fsys := c.fsys()
+	results = make(map[string]Result)
+	if err := c.validate(); err != nil {
+		return results, err
+	}
+	if c.manifest != "" {
+		if err := checkManifest(c.manifest, root); err != nil {
+			return results, err
+		}
+		defer func() {
+			if merr := writeManifest(c.manifest, c.manifestFormat, results); merr != nil {
+				err = errors.Join(err, fmt.Errorf("%w: %w", ErrManifestNotWritten, merr))
+			}
+		}()
+	}
+	if err := checkFDs(workers * fdsPerShred); err != nil {
+		return results, err
+	}
+	var files, dirs []string
+	visited := make(map[fileKey]bool) // only with WithFollowSymlinks
+	// Walks the tree at top, which was reached through a symlink if
+	// followed, so its directories are not removed.
+	var walk func(top string, followed bool) error
+	walk = func(top string, followed bool) error {
+		return walkDir(fsys, top, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+			if path != top && c.skipHidden && isHidden(d.Name(), d) {
+				results[path] = Result{Skipped: true}
+				if d.IsDir() {
+					return filepath.SkipDir
+				}
+				return nil
+			}
+			if c.followLinks {
+				if d.Type()&fs.ModeSymlink != 0 {
+					results[path] = Result{Skipped: true}
+					real, err := filepath.EvalSymlinks(path)
+					if err != nil {
+						return nil // dangling, so nothing to shred
+					}
+					return walk(real, true)
+				}
+				info, err := d.Info()
+				if err != nil {
+					return err
+				}
+				key := fileID(path, info)
+				if visited[key] {
+					if d.IsDir() {
+						return filepath.SkipDir // a cycle, or walked already
+					}
+					return nil
+				}
+				visited[key] = true
+			}
+			switch {
+			case d.IsDir():
+				if !followed {
+					dirs = append(dirs, path)
+				} else if rel, ok := within(path, root); ok {
+					// Reached through a symlink first, so skipped later.
+					dirs = append(dirs, filepath.Join(root, rel))
+				}
+			case d.Type().IsRegular():
+				if c.minAge > 0 && recent(d, now().Add(-c.minAge)) {
+					results[path] = Result{Skipped: true}
+					return nil
+				}
+				files = append(files, path)
+			default:
+				results[path] = Result{Skipped: true}
+			}
+			return nil
+		})
+	}
+	if err := walk(root, false); err != nil {
+		return results, err
+	}
+	specs := make([]PathSpec, len(files))
+	for i, file := range files {
+		specs[i].Path = file
+	}
+	if c.dirProgress != nil {
+		opts = c.trackDir(opts, files)
+	}
+	if c.trim {
+		opts = append(opts[:len(opts):len(opts)], WithTrim(false)) // once for the tree, below
+	}
+	shredPaths(ctx, specs, opts, results)
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	if err := resultsErr(files, results, c); err != nil {
+		return results, err
+	}
+	sort.Strings(dirs) // every directory before those under it
+	for i := len(dirs) - 1; i >= 0; i-- {
+		// Fails when it still holds skipped entries.
+		if err := fsys.Remove(dirs[i]); err != nil {
+			c.logf(LevelWarn, "kept directory %s: %v", dirs[i], err)
+		}
+	}
+	if c.trim {
+		c.discard(filepath.Dir(root))
+	}
+	return results, nil
+}
@@ -0,0 +1,261 @@
+package tatter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func createTree(t *testing.T, root string, files []string) {
+	t.Helper()
+	for _, file := range files {
+		path := filepath.Join(root, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatalf("err: %v\n", err)
+		}
+		f, err := copyFile(t, "testdata/small.bin", path)
+		if err != nil {
+			t.Fatalf("err: %v\n", err)
+		}
+		f.Close()
+	}
+}
+
+func TestShredDir(t *testing.T) {
+	root := "testdata/test/dir"
+	createTree(t, root, []string{"a.bin", "sub/b.bin", "sub/deeper/c.bin", ".hidden"})
+	defer os.RemoveAll(root)
+	results, err := ShredDir(root)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4\n", len(results))
+	}
+	for path, r := range results {
+		if r.Err != nil || r.Skipped || r.Stats.BytesOverwritten != 8*3 {
+			t.Fatalf("unexpected result for %s: %+v\n", path, r)
+		}
+	}
+	if _, err := os.Stat(root); err == nil {
+		t.Fatalf("dir: %v, has not been removed\n", root)
+	}
+}
+
+func TestShredDirSkipHidden(t *testing.T) {
+	root := "testdata/test/hidden"
+	createTree(t, root, []string{"a.bin", "sub/b.bin", ".git/config", ".git/objects/ab", "sub/.env", "Thumbs.db"})
+	defer os.RemoveAll(root)
+	results, err := ShredDir(root, WithSkipHidden(true))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	for _, file := range []string{".git", "sub/.env", "Thumbs.db"} {
+		path := filepath.Join(root, file)
+		if !results[path].Skipped {
+			t.Fatalf("%s has not been skipped: %+v\n", file, results[path])
+		}
+		if _, err := os.Stat(path); err != nil {
+			t.Fatalf("%s has been removed: %v\n", file, err)
+		}
+	}
+	if _, ok := results[filepath.Join(root, ".git/config")]; ok {
+		t.Fatalf("walked into a hidden directory\n")
+	}
+	for _, file := range []string{"a.bin", "sub/b.bin"} {
+		if _, err := os.Stat(filepath.Join(root, file)); err == nil {
+			t.Fatalf("%s has not been removed\n", file)
+		}
+	}
+}
+
+func TestShredDirSymlink(t *testing.T) {
+	root := "testdata/test/links"
+	createTree(t, root, []string{"a.bin"})
+	defer os.RemoveAll(root)
+	link := filepath.Join(root, "link")
+	if err := os.Symlink("../../small.bin", link); err != nil {
+		t.Skipf("symlinks not supported: %v\n", err)
+	}
+	results, err := ShredDir(root)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if !results[link].Skipped {
+		t.Fatalf("symlink has not been skipped\n")
+	}
+	if _, err := os.Lstat(link); err != nil {
+		t.Fatalf("symlink has been removed: %v\n", err)
+	}
+}
+
+func TestShredDirFollowSymlinks(t *testing.T) {
+	root := "testdata/test/follow"
+	outside := "testdata/test/outside"
+	createTree(t, root, []string{"a.bin", "sub/b.bin"})
+	createTree(t, outside, []string{"c.bin"})
+	defer os.RemoveAll(root)
+	defer os.RemoveAll(outside)
+	links := map[string]string{
+		"sub/loop": "..",                  // a cycle back to root
+		"alias":    "sub/b.bin",           // a file walked already
+		"out":      "../outside",          // a directory outside root
+		"again":    "../outside/c.bin",    // a file reached twice
+		"dangling": "../outside/none.bin", // nothing to shred
+	}
+	for name, target := range links {
+		if err := os.Symlink(target, filepath.Join(root, name)); err != nil {
+			t.Skipf("symlinks not supported: %v\n", err)
+		}
+	}
+	results, err := ShredDir(root, WithFollowSymlinks(true))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	var shreded []string
+	for path, r := range results {
+		if !r.Skipped {
+			shreded = append(shreded, path)
+		}
+	}
+	want := []string{filepath.Join(root, "a.bin"), filepath.Join(root, "sub/b.bin"), filepath.Join(outside, "c.bin")}
+	if len(shreded) != len(want) {
+		t.Fatalf("expected %v shreded, got %v\n", want, shreded)
+	}
+	for _, path := range want {
+		if r, ok := results[path]; !ok || r.Skipped || r.Err != nil {
+			t.Fatalf("expected %s shreded, got %+v\n", path, r)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Fatalf("expected %s removed, got %v\n", path, err)
+		}
+	}
+	for name := range links {
+		if !results[filepath.Join(root, name)].Skipped {
+			t.Fatalf("expected symlink %s skipped\n", name)
+		}
+	}
+}
+
+func TestShredDirFollowSymlinksInside(t *testing.T) {
+	root := "testdata/test/inside"
+	createTree(t, root, []string{"a/a.bin", "z/sub/b.bin"})
+	defer os.RemoveAll(root)
+	// Walked before z itself, which is then skipped as visited.
+	if err := os.Symlink("../z", filepath.Join(root, "a/link")); err != nil {
+		t.Skipf("symlinks not supported: %v\n", err)
+	}
+	if _, err := ShredDir(root, WithFollowSymlinks(true)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if _, err := os.Lstat(filepath.Join(root, "z")); !os.IsNotExist(err) {
+		t.Fatalf("expected z removed, got %v\n", err)
+	}
+	if _, err := os.Lstat(filepath.Join(root, "a/link")); err != nil {
+		t.Fatalf("expected the symlink kept, got %v\n", err)
+	}
+}
+
+func TestShredDirNonexistent(t *testing.T) {
+	if _, err := ShredDir("testdata/test/nonexistent"); err == nil {
+		t.Fatalf("expected err, got nil\n")
+	}
+}
+
+func TestShredDirContextCancel(t *testing.T) {
+	root := "testdata/test/cancel"
+	var files []string
+	for i := 0; i < 4*workers; i++ {
+		files = append(files, fmt.Sprintf("f%02d.bin", i))
+	}
+	createTree(t, root, files)
+	defer os.RemoveAll(root)
+	before := runtime.NumGoroutine()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	slow := WithBufferTransform(func(buf []byte, pass int) {
+		cancel()
+		time.Sleep(time.Millisecond)
+	})
+	results, err := ShredDirContext(ctx, root, slow)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got: %v, want %v\n", err, context.Canceled)
+	}
+	skipped := 0
+	for path, r := range results {
+		if r.Skipped {
+			skipped++
+			if _, err := os.Stat(path); err != nil {
+				t.Fatalf("skipped file %s has been removed\n", path)
+			}
+		}
+	}
+	if skipped == 0 || len(results) != len(files) {
+		t.Fatalf("got %d results with %d skipped\n", len(results), skipped)
+	}
+	if _, err := os.Stat(root); err != nil {
+		t.Fatalf("dir has been removed: %v\n", err)
+	}
+	for i := 0; i < 100 && runtime.NumGoroutine() > before; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("%d goroutines leaked\n", n-before)
+	}
+}
+
+func TestShredDirGlobalDeadline(t *testing.T) {
+	root := "testdata/test/dirdeadline"
+	createTree(t, root, []string{"a.bin", "sub/b.bin"})
+	defer os.RemoveAll(root)
+	results, err := ShredDir(root, WithGlobalDeadline(time.Now().Add(-time.Second)))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	for path, r := range results {
+		if !r.Skipped {
+			t.Fatalf("expected %s skipped past the deadline\n", path)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(root, "sub/b.bin")); err != nil {
+		t.Fatalf("expected files past the deadline kept, got %v\n", err)
+	}
+	results, err = ShredDir(root, WithGlobalDeadline(time.Now().Add(time.Hour)))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	for path, r := range results {
+		if r.Skipped {
+			t.Fatalf("expected %s shreded before the deadline\n", path)
+		}
+	}
+}
+
+func TestShredDirMinAge(t *testing.T) {
+	root := "testdata/test/dirminage"
+	createTree(t, root, []string{"old.bin", "new.bin"})
+	defer os.RemoveAll(root)
+	old := filepath.Join(root, "old.bin")
+	if err := os.Chtimes(old, time.Now().Add(-2*time.Hour), time.Now().Add(-2*time.Hour)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	results, err := ShredDir(root, WithMinAge(time.Hour))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if r := results[old]; r.Skipped || r.Err != nil {
+		t.Fatalf("expected %s shreded, got %+v\n", old, r)
+	}
+	recent := filepath.Join(root, "new.bin")
+	if !results[recent].Skipped {
+		t.Fatalf("expected %s skipped\n", recent)
+	}
+	if _, err := os.Stat(recent); err != nil {
+		t.Fatalf("expected %s kept, got %v\n", recent, err)
+	}
+}
@@ -0,0 +1,18 @@
+//go:build !windows
+
+package tatter
+
+import "os"
+
+// Syncs the directory, so changes to its entries reach the disk.
+func syncDir(dir string) error {
+	if dir == "" {
+		dir = "."
+	}
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	return d.Sync()
+}
@@ -0,0 +1,9 @@
+//go:build windows
+
+package tatter
+
+// Directories cannot be synced on Windows, where NTFS journals
+// directory changes by itself.
+func syncDir(dir string) error {
+	return nil
+}
@@ -0,0 +1,119 @@
+//go:build !plan9
+
+package tatter
+
+import (
+	"context"
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+	"testing/iotest"
+)
+
+// Accepts writes until limit bytes, then fails as a full disk.
+type fullWriter struct {
+	n, limit int64
+	closes   int
+}
+
+func (w *fullWriter) Write(b []byte) (int, error) {
+	if w.n+int64(len(b)) > w.limit {
+		n := w.limit - w.n
+		w.n = w.limit
+		return int(n), syscall.ENOSPC
+	}
+	w.n += int64(len(b))
+	return len(b), nil
+}
+
+func (w *fullWriter) Sync() error  { return nil }
+func (w *fullWriter) Close() error { w.closes++; return nil }
+
+// Fills buffers with zeros, recording the offsets asked for.
+type offsetsSource struct {
+	offs []int64
+}
+
+func (s *offsetsSource) Fill(b []byte, off int64) error {
+	s.offs = append(s.offs, off)
+	for i := range b {
+		b[i] = 0
+	}
+	return nil
+}
+
+func TestFillUntilFull(t *testing.T) {
+	w := &fullWriter{limit: 10000}
+	written, err := fillUntilFull(w, RandomSource{}, 4096, 0)
+	if err != nil || written != 10000 {
+		t.Fatalf("got: %d, %v, want 10000, nil\n", written, err)
+	}
+	failing := RandomSource{iotest.ErrReader(errors.New("Rand err"))}
+	if _, err := fillUntilFull(w, failing, 4096, 0); err == nil {
+		t.Fatalf("expected rand err, got nil\n")
+	}
+}
+
+type TestFillFilesTable struct {
+	name     string
+	limit    int64
+	maxFiles int // that can be created, or unlimited if 0
+	want     WipeStats
+}
+
+func TestFillFiles(t *testing.T) {
+	var tests = []TestFillFilesTable{
+		{"SingleFile", 0, 0, WipeStats{BytesWritten: 10000, Files: 1}},
+		{"Bounded", 4000, 0, WipeStats{BytesWritten: 10000, Files: 3}},
+		{"BoundedExactly", 5000, 0, WipeStats{BytesWritten: 10000, Files: 3}},
+		{"NoRoomForFile", 2000, 2, WipeStats{BytesWritten: 4000, Files: 2}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Every file writes to the same disk, full at 10000 bytes.
+			disk := &fullWriter{limit: 10000}
+			files := 0
+			create := func() (wipeFile, error) {
+				if tt.maxFiles > 0 && files == tt.maxFiles {
+					return nil, syscall.ENOSPC
+				}
+				files++
+				return disk, nil
+			}
+			src := &offsetsSource{}
+			stats, err := fillFiles(create, src, 4096, tt.limit)
+			if err != nil || stats != tt.want {
+				t.Fatalf("got: %+v, %v, want %+v, nil\n", stats, err, tt.want)
+			}
+			if disk.closes != stats.Files {
+				t.Fatalf("expected %d files closed, got %d\n", stats.Files, disk.closes)
+			}
+			for i := 1; i < len(src.offs); i++ {
+				if src.offs[i] <= src.offs[i-1] {
+					t.Fatalf("expected the source offsets to go on across files, got %v\n", src.offs)
+				}
+			}
+		})
+	}
+	create := func() (wipeFile, error) { return nil, syscall.ENOSPC }
+	if _, err := fillFiles(create, RandomSource{}, 4096, 0); !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("got: %v, want %v\n", err, syscall.ENOSPC)
+	}
+}
+
+func TestShredPassDiskFull(t *testing.T) {
+	f, err := os.OpenFile("/dev/full", os.O_WRONLY, 0)
+	if err != nil {
+		t.Skipf("no /dev/full: %v\n", err)
+	}
+	defer f.Close()
+	_, _, _, err = shredPass(context.Background(), f, 100, 10, ConstantSource(0), 1, newConfig(nil), false, nil, nil)
+	if !errors.Is(err, ErrDiskFull) || !errors.Is(err, syscall.ENOSPC) {
+		t.Fatalf("expected ErrDiskFull wrapping ENOSPC, got %v\n", err)
+	}
+	var e *ShredError
+	if !errors.As(err, &e) || e.Pass != 1 || e.Written != 0 {
+		t.Fatalf("expected a *ShredError of pass 1 with nothing written, got %v\n", err)
+	}
+}
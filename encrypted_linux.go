@@ -0,0 +1,50 @@
+//go:build linux
+
+package tatter
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"syscall"
+)
+
+// Device mapper devices stacked on each other followed down to find a
+// dm-crypt one, e.g. LVM over LUKS.
+const maxStack = 8
+
+// Returns the name of the dm-crypt device holding the file described by
+// info, with its mapping name, or an empty string if there is none.
+func cryptVolume(info os.FileInfo) string {
+	st, ok := info.Sys().(*syscall.Stat_t)
+	if !ok {
+		return ""
+	}
+	dev := uint64(st.Dev)
+	major := (dev>>8)&0xfff | (dev>>32)&^0xfff
+	minor := dev&0xff | (dev>>12)&^0xff
+	return cryptDevice(fmt.Sprintf("/sys/dev/block/%d:%d", major, minor), maxStack)
+}
+
+// Looks for a dm-crypt device at the sysfs block device directory dir,
+// or under the devices it is stacked on, up to depth levels down.
+func cryptDevice(dir string, depth int) string {
+	if depth == 0 {
+		return ""
+	}
+	if uuid, err := os.ReadFile(filepath.Join(dir, "dm", "uuid")); err == nil && strings.HasPrefix(string(uuid), "CRYPT-") {
+		name, _ := os.ReadFile(filepath.Join(dir, "dm", "name"))
+		return fmt.Sprintf("dm-crypt %s", strings.TrimSpace(string(name)))
+	}
+	slaves, err := os.ReadDir(filepath.Join(dir, "slaves"))
+	if err != nil {
+		return ""
+	}
+	for _, s := range slaves {
+		if found := cryptDevice(filepath.Join(dir, "slaves", s.Name()), depth-1); found != "" {
+			return found
+		}
+	}
+	return ""
+}
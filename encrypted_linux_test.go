@@ -0,0 +1,53 @@
+package tatter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Creates the dm directory of a fake sysfs device with the given uuid
+// and name.
+func createDM(t *testing.T, dir, uuid, name string) {
+	t.Helper()
+	if err := os.MkdirAll(filepath.Join(dir, "dm"), 0755); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "dm", "uuid"), []byte(uuid+"\n"), 0644); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "dm", "name"), []byte(name+"\n"), 0644); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+}
+
+type TestCryptDeviceTable struct {
+	name string
+	dir  string
+	want string
+}
+
+func TestCryptDevice(t *testing.T) {
+	root := "testdata/test/sys"
+	defer os.RemoveAll(root)
+	createDM(t, filepath.Join(root, "luks"), "CRYPT-LUKS2-0123456789abcdef-luks-root", "luks-root")
+	createDM(t, filepath.Join(root, "lvm"), "LVM-abcdef", "vg-home")
+	createDM(t, filepath.Join(root, "lvm", "slaves", "dm-0"), "CRYPT-LUKS2-fedcba9876543210-luks-pv", "luks-pv")
+	createDM(t, filepath.Join(root, "plain"), "LVM-012345", "vg-data")
+	if err := os.MkdirAll(filepath.Join(root, "plain", "slaves", "sda2"), 0755); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	var tests = []TestCryptDeviceTable{
+		{"LUKS", "luks", "dm-crypt luks-root"},
+		{"LVMOverLUKS", "lvm", "dm-crypt luks-pv"},
+		{"LVM", "plain", ""},
+		{"Missing", "nonexistent", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cryptDevice(filepath.Join(root, tt.dir), maxStack); got != tt.want {
+				t.Fatalf("expected %q, got %q\n", tt.want, got)
+			}
+		})
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package tatter
+
+import "os"
+
+// Encrypted volumes are not detected on this platform: FileVault and
+// BitLocker can only be queried through system tools.
+func cryptVolume(info os.FileInfo) string {
+	return ""
+}
@@ -0,0 +1,20 @@
+//go:build !plan9
+
+package tatter
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Reports whether a write failed with EINTR or EAGAIN, and is worth
+// retrying.
+func retryable(err error) bool {
+	return errors.Is(err, syscall.EINTR) || errors.Is(err, syscall.EAGAIN)
+}
+
+// Reports whether err is one of the errnos produced by operating on a
+// file through a descriptor no longer valid, as when it was removed.
+func staleFile(err error) bool {
+	return errors.Is(err, syscall.EBADF) || errors.Is(err, syscall.EINVAL)
+}
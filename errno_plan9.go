@@ -0,0 +1,16 @@
+package tatter
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Plan 9 has no EAGAIN, only interrupted writes are retried.
+func retryable(err error) bool {
+	return errors.Is(err, syscall.EINTR)
+}
+
+// Plan 9 has no EBADF, only its bad argument error.
+func staleFile(err error) bool {
+	return errors.Is(err, syscall.EINVAL)
+}
@@ -0,0 +1,72 @@
+//go:build !plan9
+
+package tatter
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/fs"
+	"syscall"
+	"testing"
+)
+
+// Fails the first writes with err, after writing half of the buffer
+// when partial is set, then writes to b.
+type interruptedFile struct {
+	b          []byte
+	err        error
+	interrupts int
+	partial    bool
+}
+
+func (f *interruptedFile) ReadAt(b []byte, off int64) (int, error) {
+	return copy(b, f.b[off:]), nil
+}
+
+func (f *interruptedFile) WriteAt(b []byte, off int64) (int, error) {
+	if f.interrupts > 0 {
+		f.interrupts--
+		n := 0
+		if f.partial {
+			n = copy(f.b[off:], b[:len(b)/2])
+		}
+		return n, &fs.PathError{Op: "write", Path: f.Name(), Err: f.err}
+	}
+	return copy(f.b[off:], b), nil
+}
+
+func (f *interruptedFile) Name() string {
+	return "interrupted"
+}
+
+type TestWriteInterruptedTable struct {
+	name string
+	f    *interruptedFile
+	err  error
+}
+
+func TestWriteInterrupted(t *testing.T) {
+	var tests = []TestWriteInterruptedTable{
+		{"EINTR", &interruptedFile{err: syscall.EINTR, interrupts: 3}, nil},
+		{"EAGAIN", &interruptedFile{err: syscall.EAGAIN, interrupts: 3}, nil},
+		{"Partial", &interruptedFile{err: syscall.EINTR, interrupts: 20, partial: true}, nil},
+		{"Forever", &interruptedFile{err: syscall.EINTR, interrupts: 1 << 30}, ErrWriteInterrupted},
+		{"Other", &interruptedFile{err: syscall.EIO, interrupts: 1}, syscall.EIO},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.f.b = make([]byte, 4096)
+			c := newConfig([]Option{WithPassSources(ConstantSource(0xff))})
+			res := make(chan procResult, 1)
+			shredProc(context.Background(), tt.f, 0, 4096, 1024, ConstantSource(0xff), 0, c, nil, res)
+			r := <-res
+			if !errors.Is(r.Err, tt.err) || (tt.err == nil) != (r.Err == nil) {
+				t.Fatalf("expected %v, got %v\n", tt.err, r.Err)
+			}
+			if tt.err == nil && (r.Bytes != 4096 || !bytes.Equal(tt.f.b, bytes.Repeat([]byte{0xff}, 4096))) {
+				t.Fatalf("expected every byte written once, got %d bytes\n", r.Bytes)
+			}
+		})
+	}
+}
@@ -0,0 +1,178 @@
+package tatter
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+)
+
+// Returned when the file was removed by someone else while it was being
+// shreded. The target is already gone, which arguably was the goal, but
+// it is not known whether every pass reached the disk.
+var ErrFileVanished = errors.New("file vanished during shred")
+
+// Returned when the data read back from the file does not match the
+// data that was written to it.
+var ErrVerificationFailed = errors.New("verification failed")
+
+// Describes data read back that differs from what a deterministic pass
+// wrote, for the first byte that differs. It matches
+// ErrVerificationFailed with errors.Is.
+type VerificationError struct {
+	Pass     int
+	Offset   int64
+	Expected byte
+	Found    byte
+}
+
+func (e *VerificationError) Error() string {
+	return fmt.Sprintf("%v: pass %d expected %#02x at offset %d, found %#02x", ErrVerificationFailed, e.Pass, e.Expected, e.Offset, e.Found)
+}
+
+func (e *VerificationError) Unwrap() error {
+	return ErrVerificationFailed
+}
+
+// Returned when the file could not be locked because another process
+// holds a lock on it.
+var ErrFileLocked = errors.New("file is locked")
+
+// Returned when the configured passes exceed the maximum allowed.
+var ErrTooManyPasses = errors.New("too many passes")
+
+// Returned when every pass completed, so the content of the file IS
+// wiped, but the file itself could not be removed, e.g. because of its
+// permissions or an immutable flag. Only the name and metadata remain.
+var ErrRemoveFailedAfterScrub = errors.New("file scrubbed but not removed")
+
+// Returned when the path to shred is not a regular file, e.g. it is a
+// symlink, a directory, a device, a pipe or a socket.
+var ErrNotRegularFile = errors.New("not a regular file")
+
+// Returned when resuming from a checkpoint saved by a shred with other
+// options, or of a file of a different size.
+var ErrCheckpointMismatch = errors.New("checkpoint does not match the shred")
+
+// Returned when the confirmation callback declined to shred the file.
+var ErrNotConfirmed = errors.New("shred not confirmed")
+
+// Returned when the file to shred is the running executable, which is
+// usually a mistake. Programs deleting themselves on purpose must use
+// WithForce.
+var ErrSelfShred = errors.New("file is the running executable")
+
+// Returned by ShredDir before shreding anything when the worker pool
+// could open more files at once than the descriptor limit allows.
+var ErrInsufficientFDs = errors.New("insufficient file descriptors")
+
+// Returned by ShredIfUnchanged when the file is not the one of the
+// snapshot anymore, or it was modified since.
+var ErrChanged = errors.New("file changed since its snapshot")
+
+// Returned by WithRandCheck when the source of randomness is broken.
+var ErrWeakRandomSource = errors.New("weak random source")
+
+// Returned by ShredRanges when a range is reversed or out of the file.
+var ErrInvalidRange = errors.New("invalid range")
+
+// Returned by VerifyAuditLog when the hash chain of the log is broken.
+var ErrAuditTampered = errors.New("audit log tampered with")
+
+// Returned when a file reports a size of 0 but holds data, so how much
+// of it to overwrite is unknown. See WithExplicitSize.
+var ErrUnknownSize = errors.New("unknown file size")
+
+// Returned by the methods of a Shredder once it is shut down.
+var ErrShredderClosed = errors.New("shredder closed")
+
+// Returned, wrapped in a *ShredError, when a write is still interrupted
+// by signals, with EINTR or EAGAIN, after const writeRetries retries.
+var ErrWriteInterrupted = errors.New("write interrupted")
+
+// Returned, joined to the outcome of ShredDir, when the manifest of
+// WithManifest could not be written. The files were shreded regardless.
+var ErrManifestNotWritten = errors.New("manifest not written")
+
+// Returned by WithDurableRemove along with WithStrictDurability when the
+// filesystem of the file cannot make its removal durable.
+var ErrDurabilityUnsupported = errors.New("durability unsupported")
+
+// Returned by WithRemovalCheck when the removed path is still there
+// after polling it, or appears again.
+var ErrRemovalNotConfirmed = errors.New("removal not confirmed")
+
+// Returned, wrapping the error of the hook, when the hook of
+// WithPreRemoveHook vetoes the removal of a file it was shown
+// overwritten.
+var ErrRemovalVetoed = errors.New("removal vetoed")
+
+// Returned by VerifyRemoved when the path is still there.
+var ErrNotRemoved = errors.New("path not removed")
+
+// Returned when the file to shred is larger than the maximum file size.
+var ErrFileTooLarge = errors.New("file too large")
+
+// Returned when refusing to shred a file with other hard links, which
+// would keep it around after removing the given path.
+var ErrHardLinked = errors.New("file has other hard links")
+
+// Returned, wrapped in a *ShredError telling the offset reached, when
+// the filesystem runs out of space while overwriting. Overwriting never
+// grows the file, but the holes of a sparse file need real blocks to be
+// allocated once written, so it can fail even though the size of the
+// file stays the same.
+var ErrDiskFull = errors.New("disk full")
+
+// Returned by pass diagnostics when, after syncing a pass, the file
+// does not hold the data that pass wrote.
+var ErrPassNotPersisted = errors.New("pass not persisted")
+
+// Wraps err into ErrFileVanished when it is one of the errors produced
+// by operating on a file that no longer exists and path is indeed gone,
+// so a race with another process is not mistaken for an I/O failure.
+func (c *config) vanished(path string, err error) error {
+	if !errors.Is(err, fs.ErrNotExist) && !staleFile(err) {
+		return err
+	}
+	if _, serr := c.fsys().Lstat(path); !errors.Is(serr, fs.ErrNotExist) {
+		return err
+	}
+	return fmt.Errorf("%w: %w", ErrFileVanished, err)
+}
+
+// Describes a failure while overwriting a file on a given pass. Offset
+// is where the failing write or fill was, and Written how many bytes of
+// the pass, out of Size, were overwritten by all threads before it. On
+// the first pass, the rest of the file still holds its original content.
+type ShredError struct {
+	Path    string
+	Pass    int
+	Offset  int64
+	Written int64
+	Size    int64
+	Err     error
+}
+
+func (e *ShredError) Error() string {
+	msg := fmt.Sprintf("shred %s: pass %d failed at offset %d after overwriting %d of %d bytes", e.Path, e.Pass, e.Offset, e.Written, e.Size)
+	if e.Pass == 0 && e.Size > 0 {
+		msg += fmt.Sprintf(", ~%.0f%% of the file remains in its original form", 100*float64(e.Size-e.Written)/float64(e.Size))
+	}
+	return msg + ": " + e.Err.Error()
+}
+
+func (e *ShredError) Unwrap() error {
+	return e.Err
+}
+
+// Calls fn with every *ShredError within err, including joined ones.
+func eachShredError(err error, fn func(*ShredError)) {
+	switch e := err.(type) {
+	case *ShredError:
+		fn(e)
+	case interface{ Unwrap() []error }:
+		for _, err := range e.Unwrap() {
+			eachShredError(err, fn)
+		}
+	}
+}
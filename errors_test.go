@@ -0,0 +1,60 @@
+package tatter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"strings"
+	"testing"
+)
+
+func TestVanishedKeepsOtherErrors(t *testing.T) {
+	err := errors.New("disk on fire")
+	if got := newConfig(nil).vanished("testdata/test/nonexistent", err); got != err {
+		t.Fatalf("got: %v, want %v\n", got, err)
+	}
+	err = &fs.PathError{Op: "remove", Path: "testdata/small.bin", Err: fs.ErrNotExist}
+	if got := newConfig(nil).vanished("testdata/small.bin", err); errors.Is(got, ErrFileVanished) {
+		t.Fatalf("existing file reported as vanished\n")
+	}
+}
+
+func TestShredErrorMessage(t *testing.T) {
+	err := &ShredError{Path: "disk.img", Pass: 0, Offset: 3 << 30, Written: 3 << 30, Size: 10 << 30, Err: io.ErrShortWrite}
+	want := "shred disk.img: pass 0 failed at offset 3221225472 after overwriting 3221225472 of 10737418240 bytes, ~70% of the file remains in its original form: short write"
+	if err.Error() != want {
+		t.Fatalf("got: %q, want %q\n", err.Error(), want)
+	}
+	if !errors.Is(err, io.ErrShortWrite) {
+		t.Fatalf("error does not unwrap\n")
+	}
+	err.Pass = 1
+	if strings.Contains(err.Error(), "original form") {
+		t.Fatalf("later pass reported as leaving original data: %v\n", err)
+	}
+}
+
+func TestShredErrorWriteFailure(t *testing.T) {
+	f, err := createFailing(t, "testdata/test/shrederr.bin")
+	if err != nil {
+		t.Fatalf("failing file not created")
+	}
+	defer f.Close()
+	err = shredFile(context.Background(), f, newConfig(nil), &ShredStats{})
+	var serr *ShredError
+	if !errors.As(err, &serr) {
+		t.Fatalf("got: %v, want *ShredError\n", err)
+	}
+	if serr.Pass != 0 || serr.Offset != 0 || serr.Written != 0 || serr.Size != 8 || !errors.Is(err, errInjected) {
+		t.Fatalf("unexpected error detail %+v\n", serr)
+	}
+}
+
+func TestVerificationErrorMessage(t *testing.T) {
+	err := &VerificationError{Pass: 1, Offset: 512, Expected: 0xFF, Found: 0x00}
+	want := "verification failed: pass 1 expected 0xff at offset 512, found 0x00"
+	if err.Error() != want {
+		t.Fatalf("expected %q, got %q\n", want, err.Error())
+	}
+}
@@ -0,0 +1,84 @@
+package tatter
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+const calibSize int64 = 1024 * 1024 // 1MiB
+
+// Estimates how long shredding the file at path with the given options
+// would take. It times a calibration write of random data, synced to
+// the device, over the first MiB of the file itself, so the estimate
+// reflects the storage the file lives on, and extrapolates it to the
+// size of the file times the number of passes. The calibration region
+// is then overwritten again with fresh random data, so the calibration
+// write is not what ends up there. Note the file is modified: call it
+// only on files that are going to be shreded. The options and the file
+// go through the checks of Shred, in the same order, before the file is
+// opened, so symlinks and other non-regular files are refused untouched.
+// The estimate is rough: a single small write does not capture caching,
+// partition parallelism nor throughput changes along large files, so
+// expect it to be off by a factor of 2 or more.
+func EstimateDuration(path string, opts ...Option) (time.Duration, error) {
+	c := newConfig(opts)
+	if err := c.validate(); err != nil {
+		return 0, err
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return 0, err
+	}
+	if !info.Mode().IsRegular() {
+		return 0, fmt.Errorf("%w: %s is %v", ErrNotRegularFile, path, info.Mode().Type())
+	}
+	if err := c.checkSelf(path, info); err != nil {
+		return 0, err
+	}
+	if err := c.checkAuditLog(path, info); err != nil {
+		return 0, err
+	}
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return 0, err
+	}
+	defer f.Close()
+	stat, err := f.Stat()
+	if err != nil {
+		return 0, err
+	}
+	if !stat.Mode().IsRegular() || !os.SameFile(info, stat) {
+		return 0, fmt.Errorf("%w: %s changed before it was opened", ErrNotRegularFile, path)
+	}
+	if err := c.checkSize(path, stat.Size()); err != nil {
+		return 0, err
+	}
+	n := stat.Size()
+	if n > calibSize {
+		n = calibSize
+	}
+	if n == 0 {
+		return 0, nil
+	}
+	b := make([]byte, n)
+	start := now()
+	if err := (RandomSource{}).Fill(b, 0); err != nil {
+		return 0, err
+	}
+	if _, err := f.WriteAt(b, 0); err != nil {
+		return 0, err
+	}
+	if err := f.Sync(); err != nil {
+		return 0, err
+	}
+	elapsed := now().Sub(start)
+	if err := (RandomSource{}).Fill(b, 0); err != nil {
+		return 0, err
+	}
+	if _, err := f.WriteAt(b, 0); err != nil {
+		return 0, err
+	}
+	total := stat.Size() * int64(len(c.sources))
+	return time.Duration(float64(elapsed) * float64(total) / float64(n)), nil
+}
@@ -0,0 +1,67 @@
+package tatter
+
+import (
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestEstimateDuration(t *testing.T) {
+	path := "testdata/test/estimate.bin"
+	f, err := copyFile(t, "testdata/extra.bin", path)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove(path)
+	defer f.Close()
+	d, err := EstimateDuration(path)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if d <= 0 {
+		t.Fatalf("expected a positive estimate, got %v\n", d)
+	}
+	if patternIn(t, "Extra123/", f) {
+		t.Fatalf("calibration region not overwritten\n")
+	}
+}
+
+func TestEstimateDurationEmpty(t *testing.T) {
+	path := "testdata/test/estimate0.bin"
+	f, err := copyFile(t, "testdata/empty.bin", path)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove(path)
+	if d, err := EstimateDuration(path); err != nil || d != 0 {
+		t.Fatalf("got: %v, %v, want 0, nil\n", d, err)
+	}
+	if _, err := EstimateDuration("testdata/test/nonexistent"); err == nil {
+		t.Fatalf("expected err, got nil\n")
+	}
+}
+
+func TestEstimateDurationNotRegular(t *testing.T) {
+	target := "testdata/test/estimatetarget.bin"
+	f, err := copyFile(t, "testdata/small.bin", target)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove(target)
+	link := "testdata/test/estimatelink.bin"
+	if err := os.Symlink("estimatetarget.bin", link); err != nil {
+		t.Skipf("symlinks not supported: %v\n", err)
+	}
+	defer os.Remove(link)
+	if _, err := EstimateDuration(link); !errors.Is(err, ErrNotRegularFile) {
+		t.Fatalf("got: %v, want %v\n", err, ErrNotRegularFile)
+	}
+	if _, err := EstimateDuration("testdata/test"); !errors.Is(err, ErrNotRegularFile) {
+		t.Fatalf("got: %v, want %v\n", err, ErrNotRegularFile)
+	}
+	if b, err := os.ReadFile(target); err != nil || string(b) != "Small123" {
+		t.Fatalf("expected the symlink target untouched: %v\n", err)
+	}
+}
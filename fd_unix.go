@@ -0,0 +1,57 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package tatter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Shreds the content of the regular file open as fd in place, the same
+// way Shred does, and syncs it. Nothing is renamed or removed, since
+// there is no path to it. The caller retains ownership of fd, which
+// must be open for writing: it is duplicated to shred the file, and
+// left open for the caller to close. Locking is not supported, since
+// the lock would outlive the call on the caller's fd.
+func ShredFd(fd uintptr, opts ...Option) error {
+	c := newConfig(opts)
+	if err := c.validate(); err != nil {
+		return err
+	}
+	dup, err := syscall.Dup(int(fd))
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("fd %d", fd)
+	f := os.NewFile(uintptr(dup), name)
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if !info.Mode().IsRegular() {
+		return fmt.Errorf("%w: %s is %v", ErrNotRegularFile, name, info.Mode().Type())
+	}
+	size, err := c.extent(f, info)
+	if err != nil {
+		return err
+	}
+	if err := c.checkSize(name, size); err != nil {
+		return err
+	}
+	if err := shredFile(context.Background(), f, c, &ShredStats{Path: name}); err != nil {
+		eachShredError(err, func(e *ShredError) { e.Path = name })
+		return err
+	}
+	if c.sentinel {
+		if err := writeSentinel(f, size, now()); err != nil {
+			return err
+		}
+	}
+	if c.syncPolicy == SyncNever {
+		return nil
+	}
+	return f.Sync()
+}
@@ -0,0 +1,50 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package tatter
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"testing"
+)
+
+func TestShredFd(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/fd.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/fd.bin")
+	defer f.Close()
+	if err := ShredFd(f.Fd(), WithStandard(StandardQuick)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	// The fd is still owned by, and usable through, f.
+	b := make([]byte, 40716)
+	if _, err := f.ReadAt(b, 0); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if !bytes.Equal(b, make([]byte, 40716)) {
+		t.Fatalf("file not overwritten with zeros\n")
+	}
+	if _, err := os.Stat("testdata/test/fd.bin"); err != nil {
+		t.Fatalf("file removed: %v\n", err)
+	}
+}
+
+func TestShredFdNotRegular(t *testing.T) {
+	f, err := os.Open("testdata")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer f.Close()
+	if err := ShredFd(f.Fd()); !errors.Is(err, ErrNotRegularFile) {
+		t.Fatalf("err: %v, want ErrNotRegularFile\n", err)
+	}
+}
+
+func TestShredFdInvalid(t *testing.T) {
+	if err := ShredFd(^uintptr(0)); err == nil {
+		t.Fatalf("err: nil, want a bad fd\n")
+	}
+}
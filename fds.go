@@ -0,0 +1,28 @@
+package tatter
+
+import (
+	"fmt"
+	"os"
+)
+
+// Descriptors a single shred may hold open at once: the file, the
+// random file, a directory or alternate stream, and a checkpoint.
+const fdsPerShred = 4
+
+// Returns ErrInsufficientFDs if fewer than n descriptors are free under
+// the limit of the process. Descriptors already open are counted from
+// /dev/fd, or assumed to be the standard three if it cannot be listed.
+func checkFDs(n uint64) error {
+	limit := fdLimit()
+	if limit == 0 {
+		return nil
+	}
+	open := uint64(3)
+	if fds, err := os.ReadDir("/dev/fd"); err == nil {
+		open = uint64(len(fds))
+	}
+	if open+n > limit {
+		return fmt.Errorf("%w: %d needed, %d of the limit of %d open", ErrInsufficientFDs, n, open, limit)
+	}
+	return nil
+}
@@ -0,0 +1,9 @@
+//go:build !unix
+
+package tatter
+
+// Returns 0, since there is no descriptor limit to query: Windows only
+// limits handles to millions per process.
+func fdLimit() uint64 {
+	return 0
+}
@@ -0,0 +1,15 @@
+//go:build unix
+
+package tatter
+
+import "syscall"
+
+// Returns the soft limit of open file descriptors of the process, or 0
+// if it cannot be queried.
+func fdLimit() uint64 {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil {
+		return 0
+	}
+	return uint64(rl.Cur)
+}
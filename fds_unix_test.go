@@ -0,0 +1,58 @@
+//go:build unix
+
+package tatter
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+type TestFDsTable struct {
+	name string
+	n    uint64
+	want error
+}
+
+func TestCheckFDs(t *testing.T) {
+	limit := fdLimit()
+	if limit == 0 {
+		t.Fatalf("expected a descriptor limit\n")
+	}
+	var tests = []TestFDsTable{
+		{"One", 1, nil},
+		{"Pool", workers * fdsPerShred, nil},
+		{"Limit", limit, ErrInsufficientFDs},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkFDs(tt.n); !errors.Is(err, tt.want) {
+				t.Fatalf("got: %v, want %v\n", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestShredDirFDs(t *testing.T) {
+	var rl syscall.Rlimit
+	if err := syscall.Getrlimit(syscall.RLIMIT_NOFILE, &rl); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer syscall.Setrlimit(syscall.RLIMIT_NOFILE, &rl)
+	low := rl
+	low.Cur = 10
+	if err := syscall.Setrlimit(syscall.RLIMIT_NOFILE, &low); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := os.MkdirAll("testdata/test/fds", 0755); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.RemoveAll("testdata/test/fds")
+	if _, err := ShredDir("testdata/test/fds"); !errors.Is(err, ErrInsufficientFDs) {
+		t.Fatalf("expected ErrInsufficientFDs, got %v\n", err)
+	}
+	if _, err := os.Stat("testdata/test/fds"); err != nil {
+		t.Fatalf("expected the directory untouched, got %v\n", err)
+	}
+}
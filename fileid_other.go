@@ -0,0 +1,17 @@
+//go:build !unix
+
+package tatter
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Returns the key identifying the file at path described by info, its
+// absolute path, since info holds no file index on this platform.
+func fileID(path string, info os.FileInfo) fileKey {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	return fileKey{path: path}
+}
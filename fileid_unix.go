@@ -0,0 +1,16 @@
+//go:build unix
+
+package tatter
+
+import (
+	"os"
+	"syscall"
+)
+
+// Returns the key identifying the file at path described by info.
+func fileID(path string, info os.FileInfo) fileKey {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return fileKey{dev: uint64(st.Dev), ino: uint64(st.Ino)}
+	}
+	return fileKey{path: path}
+}
@@ -0,0 +1,172 @@
+package tatter
+
+import (
+	"errors"
+	"io"
+	"io/fs"
+	"os"
+	"syscall"
+)
+
+// Summary of a free space wipe.
+type WipeStats struct {
+	BytesWritten int64 // before the filesystem ran out of space
+	Files        int   // temporary files filled
+}
+
+// Wipes the free space of the filesystem holding dir, so data of files
+// removed without shredding cannot be recovered. A temporary file is
+// created in dir itself, not in os.TempDir which may be another mount,
+// and filled with data from the first pass source of the options, drawn
+// from the source of randomness of the options if it is random, until
+// the filesystem is full, then synced and removed. With
+// WithWipeFileSize, several temporary files of up to that size are
+// filled in turn instead, and all of them kept until the filesystem is
+// full. dir must be an existing writable directory of the filesystem to
+// wipe. Returns the number of bytes written before the filesystem ran
+// out of space. Blocks reserved for the superuser, and space used by
+// other files in the meantime, are not wiped.
+func WipeFreeSpace(dir string, opts ...Option) (int64, error) {
+	stats, err := WipeFreeSpaceWithStats(dir, opts...)
+	return stats.BytesWritten, err
+}
+
+// Same as WipeFreeSpace, but also returns how many temporary files
+// were filled, along with the bytes written, as far as it got on error.
+func WipeFreeSpaceWithStats(dir string, opts ...Option) (WipeStats, error) {
+	c := newConfig(opts)
+	if err := c.validate(); err != nil {
+		return WipeStats{}, err
+	}
+	info, err := os.Stat(dir)
+	if err != nil {
+		return WipeStats{}, err
+	}
+	if !info.IsDir() {
+		return WipeStats{}, &fs.PathError{Op: "wipe", Path: dir, Err: syscall.ENOTDIR}
+	}
+	avail, err := freeSpace(dir)
+	if err != nil {
+		return WipeStats{}, &fs.PathError{Op: "statfs", Path: dir, Err: err}
+	}
+	c, closeRandom, err := c.openRandom()
+	if err != nil {
+		return WipeStats{}, err
+	}
+	defer closeRandom()
+	src, err := passSource(c.sources[0], 0)
+	if err != nil {
+		return WipeStats{}, err
+	}
+	src = c.random(src, 0)
+	// Only names are kept, the files are closed once filled, as there
+	// may be thousands of them.
+	var names []string
+	defer func() {
+		for _, name := range names {
+			os.Remove(name)
+		}
+	}()
+	create := func() (wipeFile, error) {
+		f, err := os.CreateTemp(dir, ".tatter-wipe-*")
+		if err != nil {
+			return nil, err
+		}
+		names = append(names, f.Name())
+		return f, nil
+	}
+	bufSize := avail
+	if c.wipeFileSize > 0 && c.wipeFileSize < bufSize {
+		bufSize = c.wipeFileSize
+	}
+	return fillFiles(create, src, c.bufSize(bufSize), c.wipeFileSize)
+}
+
+// Temporary file filled by WipeFreeSpace.
+type wipeFile interface {
+	io.WriteCloser
+	Sync() error
+}
+
+// Returned by a boundedWriter once it took all the bytes it accepts.
+var errFileLimit = errors.New("file size limit reached")
+
+// Writes up to n bytes to w, then fails with errFileLimit.
+type boundedWriter struct {
+	w io.Writer
+	n int64
+}
+
+func (b *boundedWriter) Write(p []byte) (int, error) {
+	short := int64(len(p)) > b.n
+	if short {
+		p = p[:b.n]
+	}
+	n, err := b.w.Write(p)
+	b.n -= int64(n)
+	if err == nil && short {
+		err = errFileLimit
+	}
+	return n, err
+}
+
+// Fills files made by create in turn, each one up to limit bytes, or
+// with no limit if it is 0, until one of them reports the disk is full
+// or no more can be created, syncing and closing each one once filled.
+// Each file goes on with the data of src where the previous one ended,
+// so deterministic sources do not repeat themselves.
+func fillFiles(create func() (wipeFile, error), src PassSource, bufSize, limit int64) (WipeStats, error) {
+	var stats WipeStats
+	for {
+		f, err := create()
+		if isDiskFull(err) && stats.Files > 0 {
+			return stats, nil // no room left for another file
+		}
+		if err != nil {
+			return stats, err
+		}
+		stats.Files++
+		var w io.Writer = f
+		if limit > 0 {
+			w = &boundedWriter{w: f, n: limit}
+		}
+		n, err := fillUntilFull(w, src, bufSize, stats.BytesWritten)
+		stats.BytesWritten += n
+		bounded := errors.Is(err, errFileLimit)
+		if err == nil || bounded {
+			if err = f.Sync(); isDiskFull(err) {
+				err = nil
+			}
+		}
+		if cerr := f.Close(); err == nil && !isDiskFull(cerr) {
+			err = cerr
+		}
+		if err != nil {
+			return stats, err
+		}
+		if !bounded {
+			return stats, nil
+		}
+	}
+}
+
+// Writes data from src sequentially to w, in batches of bufSize bytes,
+// starting with the data src has at offset from, until w reports the
+// disk is full. Returns the number of bytes written.
+func fillUntilFull(w io.Writer, src PassSource, bufSize, from int64) (int64, error) {
+	b := make([]byte, bufSize)
+	var written int64
+	for {
+		if err := src.Fill(b, from+written); err != nil {
+			return written, err
+		}
+		n, err := w.Write(b)
+		written += int64(n)
+		if isDiskFull(err) {
+			return written, nil
+		}
+		if err != nil {
+			return written, err
+		}
+	}
+}
@@ -0,0 +1,19 @@
+//go:build !linux && !darwin && !freebsd && !dragonfly && !windows && !plan9
+
+package tatter
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Free space is not queried on this platform, so buffers are sized as
+// for a large file and the wipe just goes on until the disk is full.
+func freeSpace(path string) (int64, error) {
+	return maxBuf * diskWrites, nil
+}
+
+// Reports whether err means the filesystem ran out of space.
+func isDiskFull(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
@@ -0,0 +1,13 @@
+package tatter
+
+// Free space is not queried on Plan 9, so buffers are sized as for a
+// large file and the wipe just goes on until the disk is full.
+func freeSpace(path string) (int64, error) {
+	return maxBuf * diskWrites, nil
+}
+
+// Plan 9 has no errnos, each file server words a full disk its own way,
+// so it is never recognized and the write error is returned as is.
+func isDiskFull(err error) bool {
+	return false
+}
@@ -0,0 +1,23 @@
+//go:build linux || darwin || freebsd || dragonfly
+
+package tatter
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Returns the bytes available to unprivileged users on the filesystem
+// holding path.
+func freeSpace(path string) (int64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, err
+	}
+	return int64(uint64(st.Bavail) * uint64(st.Bsize)), nil
+}
+
+// Reports whether err means the filesystem ran out of space.
+func isDiskFull(err error) bool {
+	return errors.Is(err, syscall.ENOSPC)
+}
@@ -0,0 +1,33 @@
+package tatter
+
+import (
+	"errors"
+	"io/fs"
+	"syscall"
+	"testing"
+)
+
+func TestWipeFreeSpaceInvalidDir(t *testing.T) {
+	if _, err := WipeFreeSpace("testdata/test/nonexistent"); err == nil {
+		t.Fatalf("expected err, got nil\n")
+	}
+	if _, err := WipeFreeSpace("testdata/small.bin"); !errors.Is(err, syscall.ENOTDIR) {
+		t.Fatalf("got: %v, want %v\n", err, syscall.ENOTDIR)
+	}
+	if _, err := WipeFreeSpace("testdata/test", WithWipeFileSize(0)); err == nil {
+		t.Fatalf("expected a wipe file size of 0 refused\n")
+	}
+	if _, err := WipeFreeSpace("testdata/test", WithRandFile("testdata/test/missing.rand")); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected the missing random file reported, got %v\n", err)
+	}
+}
+
+func TestFreeSpace(t *testing.T) {
+	avail, err := freeSpace("testdata")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if avail <= 0 {
+		t.Fatalf("expected available space, got %d\n", avail)
+	}
+}
@@ -0,0 +1,141 @@
+package tatter
+
+import (
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Minimal filesystem the package shreds files through, so applications
+// abstracting theirs, or tests keeping files in memory, can go through
+// the whole shred, removal included. The default is the real one, of
+// the os package.
+type FileSystem interface {
+	OpenFile(name string, flag int, perm os.FileMode) (File, error)
+	Lstat(name string) (os.FileInfo, error)
+	ReadDir(name string) ([]os.DirEntry, error)
+	Remove(name string) error
+}
+
+// Open file of a FileSystem. Implemented by *os.File.
+type File interface {
+	io.ReaderAt
+	io.WriterAt
+	io.Closer
+	Name() string
+	Stat() (os.FileInfo, error)
+	Sync() error
+	Truncate(size int64) error
+}
+
+// The real filesystem, of the os package.
+type osFS struct{}
+
+func (osFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	f, err := os.OpenFile(name, flag, perm)
+	if err != nil {
+		return nil, err // not a nil *os.File in a non nil File
+	}
+	return f, nil
+}
+
+func (osFS) Lstat(name string) (os.FileInfo, error)     { return os.Lstat(name) }
+func (osFS) ReadDir(name string) ([]os.DirEntry, error) { return os.ReadDir(name) }
+func (osFS) Remove(name string) error                   { return remove(name) }
+func (osFS) SameFile(a, b os.FileInfo) bool             { return os.SameFile(a, b) }
+
+// Returns the filesystem to shred files through.
+func (c *config) fsys() FileSystem {
+	if c.fs == nil {
+		return osFS{}
+	}
+	return c.fs
+}
+
+// Reports whether a and b describe the same file, as the filesystem
+// tells if it has a SameFile method like os.SameFile, or otherwise if
+// they agree on name, mode and modification time.
+func (c *config) sameFile(a, b os.FileInfo) bool {
+	if s, ok := c.fsys().(interface{ SameFile(a, b os.FileInfo) bool }); ok {
+		return s.SameFile(a, b)
+	}
+	return a.Name() == b.Name() && a.Mode() == b.Mode() && a.ModTime().Equal(b.ModTime())
+}
+
+// Returns the name of the first option set that only works on the os
+// filesystem, or an empty string if there is none.
+func (c *config) osOnly() string {
+	switch {
+	case c.lock:
+		return "WithLock"
+	case c.xattrs:
+		return "WithScrubXattrs"
+	case c.punchHoles:
+		return "WithPunchHoles"
+	case c.renames > 0:
+		return "WithRename"
+	case c.noOverwrite:
+		return "WithoutOverwrite"
+	case c.recreate:
+		return "WithRecreateSmall"
+	case c.trim:
+		return "WithTrim"
+	case c.finalName != "":
+		return "WithFinalName"
+	case c.durableRemove:
+		return "WithDurableRemove"
+	case c.decoy:
+		return "WithDecoy"
+	case c.openCheck:
+		return "WithOpenCheck"
+	case c.followLinks:
+		return "WithFollowSymlinks"
+	case c.snapshot != nil:
+		return "ShredIfUnchanged"
+	}
+	return ""
+}
+
+// Same as filepath.WalkDir, but walks the tree at root in fsys.
+func walkDir(fsys FileSystem, root string, fn fs.WalkDirFunc) error {
+	info, err := fsys.Lstat(root)
+	if err != nil {
+		err = fn(root, nil, err)
+	} else {
+		err = walkEntry(fsys, root, fs.FileInfoToDirEntry(info), fn)
+	}
+	if err == filepath.SkipDir || err == filepath.SkipAll {
+		return nil
+	}
+	return err
+}
+
+// Walks the entry d at path, and everything under it if a directory.
+func walkEntry(fsys FileSystem, path string, d fs.DirEntry, fn fs.WalkDirFunc) error {
+	if err := fn(path, d, nil); err != nil || !d.IsDir() {
+		if err == filepath.SkipDir && d.IsDir() {
+			err = nil // skipped d itself
+		}
+		return err
+	}
+	entries, err := fsys.ReadDir(path)
+	if err != nil {
+		// Second call, to report the error reading the directory.
+		if err = fn(path, d, err); err != nil {
+			if err == filepath.SkipDir {
+				err = nil
+			}
+			return err
+		}
+	}
+	for _, e := range entries {
+		if err := walkEntry(fsys, filepath.Join(path, e.Name()), e, fn); err != nil {
+			if err == filepath.SkipDir {
+				break
+			}
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package tatter
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// In-memory FileSystem, where directories are those holding the files
+// it was made with.
+type memFS struct {
+	mu    sync.Mutex
+	files map[string]*memFile
+	dirs  map[string]bool
+}
+
+type memFile struct {
+	mu   sync.Mutex
+	name string
+	data []byte
+}
+
+type memInfo struct {
+	name string
+	size int64
+	mode os.FileMode
+}
+
+func (i memInfo) Name() string       { return i.name }
+func (i memInfo) Size() int64        { return i.size }
+func (i memInfo) Mode() os.FileMode  { return i.mode }
+func (i memInfo) ModTime() time.Time { return time.Time{} }
+func (i memInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i memInfo) Sys() any           { return nil }
+
+func newMemFS(files map[string]string) *memFS {
+	m := &memFS{files: make(map[string]*memFile), dirs: make(map[string]bool)}
+	for name, data := range files {
+		m.files[name] = &memFile{name: name, data: []byte(data)}
+		for dir := filepath.Dir(name); dir != "."; dir = filepath.Dir(dir) {
+			m.dirs[dir] = true
+		}
+	}
+	return m
+}
+
+func (m *memFS) OpenFile(name string, flag int, perm os.FileMode) (File, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f, ok := m.files[name]; ok {
+		return f, nil
+	}
+	return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *memFS) Lstat(name string) (os.FileInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if f, ok := m.files[name]; ok {
+		return f.Stat()
+	}
+	if m.dirs[name] {
+		return memInfo{name: filepath.Base(name), mode: fs.ModeDir | 0755}, nil
+	}
+	return nil, &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrNotExist}
+}
+
+func (m *memFS) ReadDir(name string) ([]os.DirEntry, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var entries []os.DirEntry
+	for file, f := range m.files {
+		if filepath.Dir(file) == name {
+			info, _ := f.Stat()
+			entries = append(entries, fs.FileInfoToDirEntry(info))
+		}
+	}
+	for dir := range m.dirs {
+		if filepath.Dir(dir) == name {
+			entries = append(entries, fs.FileInfoToDirEntry(memInfo{name: filepath.Base(dir), mode: fs.ModeDir | 0755}))
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
+	return entries, nil
+}
+
+func (m *memFS) Remove(name string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.files[name]; ok {
+		delete(m.files, name)
+		return nil
+	}
+	for path := range m.files {
+		if strings.HasPrefix(path, name+"/") {
+			return &fs.PathError{Op: "remove", Path: name, Err: fs.ErrExist}
+		}
+	}
+	delete(m.dirs, name)
+	return nil
+}
+
+func (f *memFile) ReadAt(b []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if off >= int64(len(f.data)) {
+		return 0, io.EOF
+	}
+	n := copy(b, f.data[off:])
+	if n < len(b) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+func (f *memFile) WriteAt(b []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if end := off + int64(len(b)); end > int64(len(f.data)) {
+		f.data = append(f.data, make([]byte, end-int64(len(f.data)))...)
+	}
+	return copy(f.data[off:], b), nil
+}
+
+func (f *memFile) Stat() (os.FileInfo, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return memInfo{name: filepath.Base(f.name), size: int64(len(f.data)), mode: 0644}, nil
+}
+
+func (f *memFile) Truncate(size int64) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.data = f.data[:size]
+	return nil
+}
+
+func (f *memFile) Name() string { return f.name }
+func (f *memFile) Sync() error  { return nil }
+func (f *memFile) Close() error { return nil }
+
+type TestWithFileSystemTable struct {
+	name string
+	opts []Option
+	dir  bool
+}
+
+func TestWithFileSystem(t *testing.T) {
+	var tests = []TestWithFileSystemTable{
+		{"Shred", nil, false},
+		{"Paranoid", []Option{WithParanoidVerify(true)}, false},
+		{"ShredDir", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			const data = "the secret is in memory"
+			m := newMemFS(map[string]string{"root/a.txt": data, "root/sub/b.txt": data})
+			files := []*memFile{m.files["root/a.txt"], m.files["root/sub/b.txt"]}
+			opts := append([]Option{WithFileSystem(m)}, tt.opts...)
+			if tt.dir {
+				if _, err := ShredDir("root", opts...); err != nil {
+					t.Fatalf("err: %v\n", err)
+				}
+				if len(m.files) != 0 || len(m.dirs) != 0 {
+					t.Fatalf("expected the tree removed, got %v and %v\n", m.files, m.dirs)
+				}
+			} else {
+				if err := Shred("root/a.txt", opts...); err != nil {
+					t.Fatalf("err: %v\n", err)
+				}
+				if _, ok := m.files["root/a.txt"]; ok {
+					t.Fatalf("expected root/a.txt removed\n")
+				}
+				files = files[:1]
+			}
+			for _, f := range files {
+				if len(f.data) != len(data) || bytes.Equal(f.data, []byte(data)) {
+					t.Fatalf("expected %s overwritten in place, got %q\n", f.name, f.data)
+				}
+			}
+		})
+	}
+}
+
+func TestWithFileSystemRefusesOSOptions(t *testing.T) {
+	m := newMemFS(map[string]string{"a.txt": "data"})
+	if err := Shred("a.txt", WithFileSystem(m), WithLock(true)); err == nil || !strings.Contains(err.Error(), "WithLock") {
+		t.Fatalf("expected WithLock refused, got %v\n", err)
+	}
+	if _, ok := m.files["a.txt"]; !ok {
+		t.Fatalf("expected a.txt untouched\n")
+	}
+}
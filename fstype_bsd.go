@@ -0,0 +1,25 @@
+//go:build darwin || freebsd
+
+package tatter
+
+import (
+	"os"
+	"syscall"
+)
+
+// Returns the name of the filesystem holding the open file f, or an
+// empty string if it is unknown.
+func filesystemType(f *os.File) string {
+	var st syscall.Statfs_t
+	if err := syscall.Fstatfs(int(f.Fd()), &st); err != nil {
+		return ""
+	}
+	b := make([]byte, 0, len(st.Fstypename))
+	for _, c := range st.Fstypename {
+		if c == 0 {
+			break
+		}
+		b = append(b, byte(c))
+	}
+	return string(b)
+}
@@ -0,0 +1,38 @@
+//go:build linux
+
+package tatter
+
+import (
+	"os"
+	"syscall"
+)
+
+// Filesystem names by the magic number statfs reports for them.
+var fsMagic = map[uint32]string{
+	0x0000EF53: "ext4", // also ext2 and ext3
+	0x58465342: "xfs",
+	0x9123683E: "btrfs",
+	0x2FC12FC1: "zfs",
+	0xCA451A4E: "bcachefs",
+	0xF2F52010: "f2fs",
+	0x01021994: "tmpfs",
+	0x858458F6: "ramfs",
+	0x794C7630: "overlayfs",
+	0x00006969: "nfs",
+	0xFF534D42: "cifs",
+	0xFE534D42: "smb2",
+	0x65735546: "fuse",
+	0x00004D44: "vfat",
+	0x2011BAB0: "exfat",
+	0x5346544E: "ntfs",
+}
+
+// Returns the name of the filesystem holding the open file f, or an
+// empty string if it is unknown.
+func filesystemType(f *os.File) string {
+	var st syscall.Statfs_t
+	if err := syscall.Fstatfs(int(f.Fd()), &st); err != nil {
+		return ""
+	}
+	return fsMagic[uint32(st.Type)]
+}
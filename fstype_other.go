@@ -0,0 +1,10 @@
+//go:build !linux && !darwin && !freebsd && !windows
+
+package tatter
+
+import "os"
+
+// The filesystem is not queried on this platform, so it is unknown.
+func filesystemType(f *os.File) string {
+	return ""
+}
@@ -0,0 +1,23 @@
+//go:build windows
+
+package tatter
+
+import (
+	"os"
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var procGetVolumeInformationByHandleW = kernel32.NewProc("GetVolumeInformationByHandleW")
+
+// Returns the name of the filesystem holding the open file f, e.g.
+// "ntfs" or "refs", or an empty string if it is unknown.
+func filesystemType(f *os.File) string {
+	var name [syscall.MAX_PATH + 1]uint16
+	ok, _, _ := procGetVolumeInformationByHandleW.Call(f.Fd(), 0, 0, 0, 0, 0, uintptr(unsafe.Pointer(&name[0])), uintptr(len(name)))
+	if ok == 0 {
+		return ""
+	}
+	return strings.ToLower(syscall.UTF16ToString(name[:]))
+}
@@ -1,3 +1,3 @@
 module github.com/raulojeda22/tatter
 
-go 1.18
+go 1.20
@@ -0,0 +1,10 @@
+//go:build !windows
+
+package tatter
+
+import "io/fs"
+
+// Outside Windows there are no hidden attributes, only dotfiles.
+func hiddenAttr(info fs.FileInfo) bool {
+	return false
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package tatter
+
+import (
+	"io/fs"
+	"syscall"
+)
+
+// Reports whether the file has the hidden or the system attribute.
+func hiddenAttr(info fs.FileInfo) bool {
+	data, ok := info.Sys().(*syscall.Win32FileAttributeData)
+	if !ok {
+		return false
+	}
+	return data.FileAttributes&(syscall.FILE_ATTRIBUTE_HIDDEN|syscall.FILE_ATTRIBUTE_SYSTEM) != 0
+}
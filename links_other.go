@@ -0,0 +1,10 @@
+//go:build !unix && !windows
+
+package tatter
+
+import "os"
+
+// Hard links are not reported on this platform.
+func linkCount(f *os.File, info os.FileInfo) int {
+	return 1
+}
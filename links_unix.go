@@ -0,0 +1,17 @@
+//go:build unix
+
+package tatter
+
+import (
+	"os"
+	"syscall"
+)
+
+// Returns the number of hard links of the open file f, as reported by
+// the stat it already got.
+func linkCount(f *os.File, info os.FileInfo) int {
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		return int(st.Nlink)
+	}
+	return 1
+}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package tatter
+
+import (
+	"os"
+	"syscall"
+)
+
+// Returns the number of hard links of the open file f.
+func linkCount(f *os.File, info os.FileInfo) int {
+	var d syscall.ByHandleFileInformation
+	if err := syscall.GetFileInformationByHandle(syscall.Handle(f.Fd()), &d); err != nil {
+		return 1
+	}
+	return int(d.NumberOfLinks)
+}
@@ -0,0 +1,24 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package tatter
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+// Acquires an exclusive advisory flock on f without blocking. It is
+// released when f is closed. Filesystems that do not support flock
+// (e.g. some network filesystems) are treated as best-effort and the
+// shred goes on unlocked.
+func lockFile(f *os.File) error {
+	err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB)
+	if errors.Is(err, syscall.EWOULDBLOCK) {
+		return ErrFileLocked
+	}
+	if errors.Is(err, syscall.ENOTSUP) || errors.Is(err, syscall.ENOLCK) {
+		return nil
+	}
+	return err
+}
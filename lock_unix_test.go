@@ -0,0 +1,35 @@
+//go:build darwin || dragonfly || freebsd || linux || netbsd || openbsd
+
+package tatter
+
+import (
+	"errors"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestShredLocked(t *testing.T) {
+	path := "testdata/test/locked.bin"
+	f, err := copyFile(t, "testdata/small.bin", path)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove(path)
+	defer f.Close()
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := Shred(path, WithLock(true)); !errors.Is(err, ErrFileLocked) {
+		t.Fatalf("got: %v, want %v\n", err, ErrFileLocked)
+	}
+	if !patternIn(t, "Small123", f) {
+		t.Fatalf("locked file has been overwritten\n")
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_UN); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := Shred(path, WithLock(true)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+}
@@ -0,0 +1,31 @@
+//go:build windows
+
+package tatter
+
+import (
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+var procLockFileEx = kernel32.NewProc("LockFileEx")
+
+const (
+	lockfileFailImmediately = 0x1
+	lockfileExclusiveLock   = 0x2
+	errorLockViolation      = syscall.Errno(33)
+)
+
+// Acquires an exclusive LockFileEx lock over the whole of f without
+// blocking. It is released when f is closed.
+func lockFile(f *os.File) error {
+	var ol syscall.Overlapped
+	ok, _, err := procLockFileEx.Call(f.Fd(), lockfileExclusiveLock|lockfileFailImmediately, 0, 0xFFFFFFFF, 0xFFFFFFFF, uintptr(unsafe.Pointer(&ol)))
+	if ok != 0 {
+		return nil
+	}
+	if err == errorLockViolation {
+		return ErrFileLocked
+	}
+	return err
+}
@@ -0,0 +1,75 @@
+package tatter
+
+import "fmt"
+
+// Severity of a message sent to a Logger.
+type LogLevel int
+
+const (
+	LevelDebug LogLevel = iota // every buffer written, very verbose
+	LevelInfo                  // every file shreded, the default
+	LevelWarn                  // degraded shreds that still succeeded
+	LevelError                 // files that failed to be shreded
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return fmt.Sprintf("LogLevel(%d)", int(l))
+}
+
+// Receives messages about the progress of shredding. Log may be called
+// concurrently by the goroutines writing a file or shredding several.
+type Logger interface {
+	Log(level LogLevel, msg string)
+}
+
+// Adapts a function to the Logger interface.
+type LoggerFunc func(level LogLevel, msg string)
+
+func (fn LoggerFunc) Log(level LogLevel, msg string) {
+	fn(level, msg)
+}
+
+// Sends messages to the given logger. Only messages of the configured
+// level (see WithLogLevel) or above are sent. A nil logger, the default,
+// disables logging.
+func WithLogger(l Logger) Option {
+	return func(c *config) {
+		c.logger = l
+	}
+}
+
+// Sets the minimum level of the messages sent to the logger, LevelInfo
+// by default. LevelDebug logs every buffer written, which is useful to
+// troubleshoot but slows down shredding noticeably.
+func WithLogLevel(level LogLevel) Option {
+	return func(c *config) {
+		if level < LevelDebug || level > LevelError {
+			c.err = fmt.Errorf("invalid log level %v", level)
+			return
+		}
+		c.logLevel = level
+	}
+}
+
+// Returns whether messages of the given level reach the logger, so that
+// callers can skip building messages nobody will see.
+func (c *config) logs(level LogLevel) bool {
+	return c.logger != nil && level >= c.logLevel
+}
+
+// Formats and sends a message to the logger, if its level is enabled.
+func (c *config) logf(level LogLevel, format string, args ...interface{}) {
+	if c.logs(level) {
+		c.logger.Log(level, fmt.Sprintf(format, args...))
+	}
+}
@@ -0,0 +1,93 @@
+package tatter
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// Collects the messages sent to it, by level.
+type testLogger struct {
+	mu   sync.Mutex
+	msgs map[LogLevel][]string
+}
+
+func (l *testLogger) Log(level LogLevel, msg string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.msgs == nil {
+		l.msgs = make(map[LogLevel][]string)
+	}
+	l.msgs[level] = append(l.msgs[level], msg)
+}
+
+type TestLogLevelTable struct {
+	name  string
+	level LogLevel
+	debug bool
+	info  bool
+}
+
+func TestWithLogLevel(t *testing.T) {
+	var tests = []TestLogLevelTable{
+		{"Debug", LevelDebug, true, true},
+		{"Info", LevelInfo, false, true},
+		{"Warn", LevelWarn, false, false},
+		{"Error", LevelError, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := copyFile(t, "testdata/large.bin", "testdata/test/log.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			f.Close()
+			l := &testLogger{}
+			if err := Shred("testdata/test/log.bin", WithLogger(l), WithLogLevel(tt.level)); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			if got := len(l.msgs[LevelDebug]) > 0; got != tt.debug {
+				t.Fatalf("expected debug messages %v, got %v\n", tt.debug, got)
+			}
+			if got := len(l.msgs[LevelInfo]) == 1; got != tt.info {
+				t.Fatalf("expected info message %v, got %v\n", tt.info, got)
+			}
+			if tt.info && !strings.Contains(l.msgs[LevelInfo][0], "testdata/test/log.bin") {
+				t.Fatalf("info message %q lacks the path\n", l.msgs[LevelInfo][0])
+			}
+		})
+	}
+}
+
+func TestWithLogLevelInvalid(t *testing.T) {
+	if err := Shred("testdata/small.bin", WithLogLevel(LogLevel(7))); err == nil {
+		t.Fatalf("err: nil, want invalid log level\n")
+	}
+}
+
+func TestLogError(t *testing.T) {
+	l := &testLogger{}
+	if err := Shred("testdata/test/missing.bin", WithLogger(LoggerFunc(l.Log))); err == nil {
+		t.Fatalf("err: nil, want a missing file\n")
+	}
+	if len(l.msgs[LevelError]) != 1 || len(l.msgs[LevelInfo]) != 0 {
+		t.Fatalf("messages: %v, want a single error\n", l.msgs)
+	}
+}
+
+func TestLogDefault(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/log2.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/log2.bin")
+	l := &testLogger{}
+	if err := Shred("testdata/test/log2.bin", WithLogger(l)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if len(l.msgs[LevelDebug]) != 0 || len(l.msgs[LevelInfo]) != 1 {
+		t.Fatalf("messages: %v, want a single info\n", l.msgs)
+	}
+}
@@ -0,0 +1,97 @@
+package tatter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Format of the manifest written by WithManifest.
+type ManifestFormat int
+
+const (
+	ManifestJSON ManifestFormat = iota // a JSON array of ManifestEntry
+	ManifestCSV                        // a header row, then a row per ManifestEntry
+)
+
+// Outcomes of the paths listed in a manifest.
+const (
+	OutcomeShreded = "shreded"
+	OutcomeSkipped = "skipped"
+	OutcomeFailed  = "failed"
+)
+
+// An entry of the manifest of WithManifest, one per path in the results
+// of ShredDir, sorted by path. In CSV, the columns are its fields in
+// this order, under the header path,size,passes,outcome,error,time,
+// with the time in RFC 3339.
+type ManifestEntry struct {
+	Path    string    `json:"path"`            // as found by the walk
+	Size    int64     `json:"size"`            // of the file, in bytes
+	Passes  int       `json:"passes"`          // completed
+	Outcome string    `json:"outcome"`         // OutcomeShreded, OutcomeSkipped or OutcomeFailed
+	Error   string    `json:"error,omitempty"` // why it failed, if it did
+	Time    time.Time `json:"time"`            // when ShredDir ended, in UTC
+}
+
+// Returns the entries of the manifest of the given results, at time t.
+func manifestEntries(results map[string]Result, t time.Time) []ManifestEntry {
+	entries := make([]ManifestEntry, 0, len(results))
+	for path, res := range results {
+		e := ManifestEntry{Path: path, Size: res.Stats.Size, Passes: res.Stats.Passes, Outcome: OutcomeShreded, Time: t.UTC()}
+		switch {
+		case res.Skipped:
+			e.Outcome = OutcomeSkipped
+		case res.Err != nil:
+			e.Outcome = OutcomeFailed
+			e.Error = res.Err.Error()
+		}
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Path < entries[j].Path })
+	return entries
+}
+
+// Writes the manifest of the given results to path, in the given
+// format, replacing any file there.
+func writeManifest(path string, format ManifestFormat, results map[string]Result) error {
+	entries := manifestEntries(results, now())
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+	if err != nil {
+		return err
+	}
+	switch format {
+	case ManifestCSV:
+		w := csv.NewWriter(f)
+		w.Write([]string{"path", "size", "passes", "outcome", "error", "time"})
+		for _, e := range entries {
+			w.Write([]string{e.Path, strconv.FormatInt(e.Size, 10), strconv.Itoa(e.Passes), e.Outcome, e.Error, e.Time.Format(time.RFC3339)})
+		}
+		w.Flush()
+		err = w.Error()
+	default:
+		enc := json.NewEncoder(f)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(entries)
+	}
+	if err == nil {
+		err = f.Sync()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	return err
+}
+
+// Checks the manifest path is out of the tree at root, which ShredDir
+// removes.
+func checkManifest(path, root string) error {
+	if _, ok := within(path, root); ok {
+		return fmt.Errorf("manifest %s is within %s", path, root)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package tatter
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type TestWithManifestTable struct {
+	name   string
+	format ManifestFormat
+}
+
+func TestWithManifest(t *testing.T) {
+	var tests = []TestWithManifestTable{
+		{"JSON", ManifestJSON},
+		{"CSV", ManifestCSV},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			root := "testdata/test/manifest"
+			createTree(t, root, []string{"a.bin", "sub/b.bin"})
+			defer os.RemoveAll(root)
+			if err := os.Symlink("a.bin", filepath.Join(root, "link")); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			manifest := "testdata/test/manifest." + tt.name
+			defer os.Remove(manifest)
+			if _, err := ShredDir(root, WithManifest(manifest, tt.format)); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			f, err := os.Open(manifest)
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			defer f.Close()
+			var outcomes []string
+			if tt.format == ManifestCSV {
+				rows, err := csv.NewReader(f).ReadAll()
+				if err != nil {
+					t.Fatalf("err: %v\n", err)
+				}
+				if len(rows) != 4 || rows[0][0] != "path" || rows[1][1] != "8" || rows[1][2] != "3" {
+					t.Fatalf("unexpected manifest: %v\n", rows)
+				}
+				for _, row := range rows[1:] {
+					outcomes = append(outcomes, row[3])
+				}
+			} else {
+				var entries []ManifestEntry
+				if err := json.NewDecoder(f).Decode(&entries); err != nil {
+					t.Fatalf("err: %v\n", err)
+				}
+				if len(entries) != 3 || entries[0].Size != 8 || entries[0].Passes != 3 || entries[0].Time.IsZero() {
+					t.Fatalf("unexpected manifest: %+v\n", entries)
+				}
+				for _, e := range entries {
+					outcomes = append(outcomes, e.Outcome)
+				}
+			}
+			// Sorted by path: a.bin, link, sub/b.bin.
+			want := []string{OutcomeShreded, OutcomeSkipped, OutcomeShreded}
+			for i := range want {
+				if outcomes[i] != want[i] {
+					t.Fatalf("expected outcomes %v, got %v\n", want, outcomes)
+				}
+			}
+		})
+	}
+}
+
+func TestWithManifestInsideTree(t *testing.T) {
+	root := "testdata/test/manifest2"
+	createTree(t, root, []string{"a.bin"})
+	defer os.RemoveAll(root)
+	if _, err := ShredDir(root, WithManifest(filepath.Join(root, "sub/manifest.json"), ManifestJSON)); err == nil {
+		t.Fatalf("expected a manifest within the tree refused\n")
+	}
+	if _, err := os.Stat(filepath.Join(root, "a.bin")); err != nil {
+		t.Fatalf("expected a.bin untouched, got %v\n", err)
+	}
+}
+
+func TestWithManifestNotWritten(t *testing.T) {
+	root := "testdata/test/manifest3"
+	createTree(t, root, []string{"a.bin"})
+	defer os.RemoveAll(root)
+	results, err := ShredDir(root, WithManifest("testdata/test/nonexistent/manifest.json", ManifestJSON))
+	if !errors.Is(err, ErrManifestNotWritten) {
+		t.Fatalf("expected ErrManifestNotWritten, got %v\n", err)
+	}
+	if r := results[filepath.Join(root, "a.bin")]; r.Err != nil || r.Stats.Passes != 3 {
+		t.Fatalf("expected a.bin shreded, got %+v\n", r)
+	}
+	if _, err := os.Stat(root); err == nil {
+		t.Fatalf("expected %s removed\n", root)
+	}
+}
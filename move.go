@@ -0,0 +1,87 @@
+package tatter
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+)
+
+// Moves the file at src to dst, and then shreds src with the given
+// options. The content is first copied to a temporary file next to dst,
+// synced and read back to verify it, and only then linked as dst, its
+// temporary name removed and the directory synced, so dst is never left
+// as a partial file. Linking fails if dst exists, even if created in
+// the meantime, so it is never replaced. If any step before dst is
+// durable fails, src is left intact and the temporary copy is shreded.
+// dst must not exist.
+func MoveAndShred(src, dst string, opts ...Option) error {
+	if _, err := os.Lstat(dst); err == nil {
+		return &fs.PathError{Op: "move", Path: dst, Err: fs.ErrExist}
+	}
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	info, err := in.Stat()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(dst), "."+filepath.Base(dst)+".tmp-*")
+	if err != nil {
+		return err
+	}
+	if err = copyDurable(tmp, in, info.Mode().Perm()); err != nil {
+		tmp.Close()
+		if Shred(tmp.Name()) != nil {
+			os.Remove(tmp.Name())
+		}
+		return err
+	}
+	if err = os.Link(tmp.Name(), dst); err != nil {
+		Shred(tmp.Name())
+		if errors.Is(err, fs.ErrExist) {
+			return &fs.PathError{Op: "move", Path: dst, Err: fs.ErrExist}
+		}
+		return err
+	}
+	if err = os.Remove(tmp.Name()); err != nil {
+		return err
+	}
+	if err = syncDir(filepath.Dir(dst)); err != nil {
+		return err
+	}
+	in.Close()
+	return Shred(src, opts...)
+}
+
+// Copies in to out, which is synced, verified against what was read
+// from in, given the permissions perm and closed.
+func copyDurable(out *os.File, in io.Reader, perm fs.FileMode) error {
+	h := sha256.New()
+	if _, err := io.Copy(out, io.TeeReader(in, h)); err != nil {
+		return err
+	}
+	if err := out.Chmod(perm); err != nil {
+		return err
+	}
+	if err := out.Sync(); err != nil {
+		return err
+	}
+	got := sha256.New()
+	if _, err := out.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	if _, err := io.Copy(got, out); err != nil {
+		return err
+	}
+	if !bytes.Equal(got.Sum(nil), h.Sum(nil)) {
+		return fmt.Errorf("%w: copy of %s", ErrVerificationFailed, out.Name())
+	}
+	return out.Close()
+}
@@ -0,0 +1,54 @@
+package tatter
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+func TestMoveAndShred(t *testing.T) {
+	src, dst := "testdata/test/move-src.bin", "testdata/test/move-dst.bin"
+	f, err := copyFile(t, "testdata/extra.bin", src)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove(dst)
+	if err := MoveAndShred(src, dst); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if _, err := os.Stat(src); err == nil {
+		t.Fatalf("file: %v, has not been removed\n", src)
+	}
+	want, _ := os.ReadFile("testdata/extra.bin")
+	got, err := os.ReadFile(dst)
+	if err != nil || string(got) != string(want) {
+		t.Fatalf("moved content differs: %v\n", err)
+	}
+}
+
+func TestMoveAndShredFailure(t *testing.T) {
+	src := "testdata/test/move-src2.bin"
+	f, err := copyFile(t, "testdata/small.bin", src)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove(src)
+	if err := MoveAndShred(src, "testdata/test/nonexistent/dst.bin"); err == nil {
+		t.Fatalf("expected err, got nil\n")
+	}
+	if err := MoveAndShred(src, "testdata/small.bin"); !errors.Is(err, fs.ErrExist) {
+		t.Fatalf("got: %v, want %v\n", err, fs.ErrExist)
+	}
+	if b, err := os.ReadFile(src); err != nil || string(b) != "Small123" {
+		t.Fatalf("source has been modified: %v\n", err)
+	}
+	entries, _ := os.ReadDir("testdata/test")
+	for _, e := range entries {
+		if e.Name() != ".gitkeep" && e.Name() != "move-src2.bin" {
+			t.Fatalf("leftover file %s\n", e.Name())
+		}
+	}
+}
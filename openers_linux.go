@@ -0,0 +1,37 @@
+package tatter
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+)
+
+// Returns how many other processes hold the file described by info
+// open, found by scanning their descriptors in /proc. The processes
+// whose descriptors cannot be read are not counted.
+func openByOthers(info os.FileInfo) int {
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0
+	}
+	self := os.Getpid()
+	n := 0
+	for _, p := range procs {
+		pid, err := strconv.Atoi(p.Name())
+		if err != nil || pid == self {
+			continue
+		}
+		dir := filepath.Join("/proc", p.Name(), "fd")
+		fds, err := os.ReadDir(dir)
+		if err != nil {
+			continue
+		}
+		for _, fd := range fds {
+			if fi, err := os.Stat(filepath.Join(dir, fd.Name())); err == nil && os.SameFile(info, fi) {
+				n++
+				break
+			}
+		}
+	}
+	return n
+}
@@ -0,0 +1,37 @@
+package tatter
+
+import (
+	"os"
+	"os/exec"
+	"testing"
+)
+
+func TestWithOpenCheck(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/opened.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/opened.bin")
+	defer f.Close()
+	cmd := exec.Command("sleep", "10")
+	cmd.Stdin = f
+	if err := cmd.Start(); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer cmd.Wait()
+	defer cmd.Process.Kill()
+	p, err := DryRun("testdata/test/opened.bin", WithOpenCheck(true))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if p.OpenByOthers != 1 {
+		t.Fatalf("expected the plan to count 1 other process, got %d\n", p.OpenByOthers)
+	}
+	stats, err := ShredWithStats("testdata/test/opened.bin", WithOpenCheck(true))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if stats.OpenByOthers != 1 {
+		t.Fatalf("expected the file open by 1 other process, got %d\n", stats.OpenByOthers)
+	}
+}
@@ -0,0 +1,11 @@
+//go:build !linux
+
+package tatter
+
+import "os"
+
+// Returns 0, since the files other processes hold open cannot be found
+// on this platform.
+func openByOthers(info os.FileInfo) int {
+	return 0
+}
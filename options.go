@@ -0,0 +1,1286 @@
+package tatter
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+const defMaxPasses = 100
+const maxShards = 256
+const maxHostID = 64
+
+// Configures how files are shreded. Options are applied in order, so
+// later options override earlier ones.
+type Option func(*config)
+
+type config struct {
+	err            error
+	sources        []PassSource
+	transform      func(buf []byte, pass int)
+	paranoid       bool
+	lock           bool
+	skipHidden     bool
+	sharedRand     bool
+	maxMemory      int64
+	maxPasses      int
+	force          bool
+	xattrs         bool
+	punchHoles     bool
+	writeCount     int64
+	renames        int
+	refuseLinks    bool
+	errorMode      ErrorMode
+	diagnostics    bool
+	logger         Logger
+	logLevel       LogLevel
+	shuffle        bool
+	readAfterWrite bool
+	sentinel       bool
+	sectorAlign    bool
+	forceRemove    bool
+	deadline       time.Time
+	budget         int64
+	maxSize        int64
+	lowPriority    bool
+	checkpoint     string
+	minAge         time.Duration
+	confirm        func(path string) bool
+	sequential     bool
+	durableRemove  bool
+	syncPolicy     SyncPolicy
+	trace          func(off, n int64, d time.Duration)
+	decoy          bool
+	openCheck      bool
+	xorPass        bool
+	followLinks    bool
+	rand           io.Reader
+	randFile       string
+	poolFile       string
+	randCheck      bool
+	auditLog       string
+	shards         int
+	explicitSize   int64
+	invalidate     bool
+	fs             FileSystem
+	autoTune       bool
+	blockHashes    string
+	manifest       string
+	manifestFormat ManifestFormat
+	strictDurable  bool
+	dirProgress    func(written, total int64)
+	noOverwrite    bool
+	hostID         []byte
+	removalPolls   int
+	removalDelay   time.Duration
+	encAware       bool
+	preRemove      func(path string) error
+	recreate       bool
+	trim           bool
+	finalName      string
+	wipeFileSize   int64
+	keep           bool                         // set by Scrub, not an option
+	truncate       bool                         // set by ShredAndTruncate, not an option
+	progress       func(pass int, off, n int64) // set by ShredProgress
+	snapshot       *Snapshot                    // set by ShredIfUnchanged
+	reduced        *sync.Map                    // set by shredPaths, buffer reductions warned about
+	pool           *poolSource                  // set by shred from poolFile
+	tunedThreads   int                          // set by NewShredder with autoTune
+}
+
+// How failures from several threads or files are combined into the
+// single error returned.
+type ErrorMode int
+
+const (
+	JoinAll    ErrorMode = iota // every error, joined with errors.Join
+	FirstError                  // only the first error found
+)
+
+// Combines errs as the error mode says, or returns nil if there are none.
+func (c *config) combine(errs []error) error {
+	if len(errs) == 0 {
+		return nil
+	}
+	if len(errs) == 1 || c.errorMode == FirstError {
+		return errs[0]
+	}
+	return errors.Join(errs...)
+}
+
+// When the overwritten data is flushed to the storage with fsync.
+type SyncPolicy int
+
+const (
+	SyncPerPass SyncPolicy = iota // after every pass, the default
+	SyncAtEnd                     // once, after the last pass
+	SyncNever                     // never, the OS writes back when it wants
+)
+
+// Returns a copy of c reading from the files of WithRandFile and
+// WithRandPoolFile, if any, opened, and checked with WithRandCheck,
+// along with a function closing them.
+func (c *config) openRandom() (_ *config, _ func(), err error) {
+	cc := *c
+	var files []*os.File
+	closeAll := func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}
+	defer func() {
+		if err != nil {
+			closeAll()
+		}
+	}()
+	if c.randFile != "" {
+		r, err := os.Open(c.randFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, r)
+		cc.rand = r
+	}
+	if c.randCheck && cc.rand != nil {
+		if err := checkRandom(cc.rand); err != nil {
+			return nil, nil, err
+		}
+	}
+	if c.poolFile != "" {
+		r, err := os.Open(c.poolFile)
+		if err != nil {
+			return nil, nil, err
+		}
+		files = append(files, r)
+		info, err := r.Stat()
+		if err != nil {
+			return nil, nil, err
+		}
+		if info.Size() == 0 {
+			return nil, nil, fmt.Errorf("random pool %s is empty", c.poolFile)
+		}
+		cc.pool = &poolSource{r: r, size: info.Size()}
+	}
+	return &cc, closeAll, nil
+}
+
+// Returns src, unless it is a random source left to the default, which
+// is replaced by the pool or source of randomness of the options, if
+// any, or a keystream, which is keyed with the host id, if any.
+func (c *config) random(src PassSource, pass int) PassSource {
+	if r, ok := src.(RandomSource); ok && r.R == nil {
+		switch {
+		case c.pool != nil:
+			return poolSource{c.pool.r, c.pool.size, int64(pass) * poolShift}
+		case c.rand != nil:
+			return RandomSource{c.rand}
+		}
+	}
+	if m, ok := src.(MixSource); ok && m.R == nil && c.rand != nil {
+		m.R = c.rand
+		return m
+	}
+	if c.hostID != nil {
+		switch k := src.(type) {
+		case *keystream:
+			return k.mixed(c.hostID)
+		case *uniqueBlocks:
+			u := *k
+			u.keystream = k.keystream.mixed(c.hostID)
+			return &u
+		}
+	}
+	return src
+}
+
+// Returns whether the file must be synced after the given pass.
+func (c *config) syncs(pass, passes int) bool {
+	switch c.syncPolicy {
+	case SyncPerPass:
+		return true
+	case SyncAtEnd:
+		return pass == passes-1
+	}
+	return false
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{maxPasses: defMaxPasses, logLevel: LevelInfo}
+	c.sources, _ = StandardRandom3.sources()
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Checks a file of the given size is within the maximum file size,
+// returning ErrFileTooLarge otherwise.
+func (c *config) checkSize(name string, size int64) error {
+	if c.maxSize > 0 && size > c.maxSize && !c.force {
+		return fmt.Errorf("%w: %s is %d bytes, the limit is %d", ErrFileTooLarge, name, size, c.maxSize)
+	}
+	return nil
+}
+
+// Returns how many bytes of the file f described by info to overwrite:
+// its size, or if it reports none, the explicit size of the options.
+// Without one, files reporting no bytes that still hold data, such as
+// those of procfs, fail with ErrUnknownSize.
+func (c *config) extent(f File, info os.FileInfo) (int64, error) {
+	if size := info.Size(); size > 0 {
+		return size, nil
+	}
+	if c.explicitSize > 0 {
+		return c.explicitSize, nil
+	}
+	var b [1]byte
+	if n, _ := f.ReadAt(b[:], 0); n > 0 {
+		return 0, fmt.Errorf("%w: %s reports 0 bytes but holds data", ErrUnknownSize, f.Name())
+	}
+	return 0, nil
+}
+
+// Checks the file described by info is not the running executable,
+// through any symlink or hard link, returning ErrSelfShred otherwise.
+func (c *config) checkSelf(path string, info os.FileInfo) error {
+	if c.force {
+		return nil
+	}
+	exe, err := executable()
+	if err != nil {
+		return nil // unknown, so it cannot be told apart
+	}
+	if self, err := os.Stat(exe); err == nil && os.SameFile(info, self) {
+		return fmt.Errorf("%w: %s is %s", ErrSelfShred, path, exe)
+	}
+	return nil
+}
+
+// Checks the options make sense together, before touching any file.
+func (c *config) validate() error {
+	if c.err != nil {
+		return c.err
+	}
+	if c.checkpoint != "" && c.shuffle {
+		return errors.New("checkpoints cannot resume shuffled passes")
+	}
+	if c.fs != nil {
+		if name := c.osOnly(); name != "" {
+			return fmt.Errorf("%s needs the real filesystem, not %T", name, c.fs)
+		}
+	}
+	if c.finalName != "" && c.renames > 0 {
+		return errors.New("a final name cannot be given to a file renamed at random")
+	}
+	if c.noOverwrite && (c.keep || c.decoy) {
+		return errors.New("a file kept without being overwritten would be left as it is")
+	}
+	if c.blockHashes != "" && !c.keep {
+		return errors.New("only the files kept by Scrub can be overwritten by changed blocks")
+	}
+	if c.sequential && c.shards > 1 {
+		return errors.New("a single sequential thread cannot write several shards")
+	}
+	if c.decoy {
+		if c.sentinel {
+			return errors.New("a decoy cannot carry a sentinel marker")
+		}
+		srcs := c.sources[len(c.sources)-1:]
+		if c.shuffle {
+			srcs = c.sources
+		}
+		for _, src := range srcs {
+			if deterministic(src) {
+				return fmt.Errorf("a decoy must end with a random pass, not %T", src)
+			}
+		}
+	}
+	if len(c.sources) > c.maxPasses && !c.force {
+		return fmt.Errorf("%w: %d passes, the limit is %d", ErrTooManyPasses, len(c.sources), c.maxPasses)
+	}
+	return nil
+}
+
+// Buffer size wanted for a file of the given size, before the memory
+// limit is applied.
+func (c *config) wantedBuf(size int64) int64 {
+	if c.writeCount <= 0 {
+		return calcBuf(size)
+	}
+	bufSize := (size + c.writeCount - 1) / c.writeCount
+	if bufSize < 1 {
+		bufSize = 1
+	}
+	if bufSize > maxBuf {
+		bufSize = maxBuf
+	}
+	return bufSize
+}
+
+// Buffer size to use for a file of the given size, capped so that the
+// buffers of all threads fit in the memory limit, if any.
+func (c *config) bufSize(size int64) int64 {
+	bufSize := c.wantedBuf(size)
+	if c.maxMemory > 0 && bufSize*int64(c.nthreads()) > c.maxMemory {
+		bufSize = c.maxMemory / int64(c.nthreads())
+		if bufSize < 1 {
+			bufSize = 1
+		}
+	}
+	return bufSize
+}
+
+// Warns that the memory limit reduced the buffers from want to got
+// bytes, unless the same reduction was warned about already.
+func (c *config) warnReduced(want, got int64) {
+	if c.reduced != nil {
+		if _, dup := c.reduced.LoadOrStore([2]int64{want, got}, true); dup {
+			return
+		}
+	}
+	c.logf(LevelWarn, "max memory of %d bytes reduced the buffers from %d to %d bytes", c.maxMemory, want, got)
+}
+
+// Rounds bufSize up to a multiple of block, or down if that breaks the
+// memory limit, but never below a single block.
+func (c *config) alignBuf(bufSize, block int64) int64 {
+	if block <= 1 {
+		return bufSize
+	}
+	aligned := (bufSize + block - 1) / block * block
+	if c.maxMemory > 0 && aligned*int64(c.nthreads()) > c.maxMemory {
+		aligned = bufSize / block * block
+	}
+	if aligned < block {
+		aligned = block
+	}
+	return aligned
+}
+
+// Number of threads overwriting a file on every pass.
+func (c *config) nthreads() int {
+	switch {
+	case c.sequential:
+		return 1
+	case c.shards > 0:
+		return c.shards
+	case c.tunedThreads > 0:
+		return c.tunedThreads
+	}
+	return threads
+}
+
+// Memory that may be used for buffers of a single file. Unless limited
+// with WithMaxMemory, it is as much as the buffers of all threads could
+// take for a large file.
+func (c *config) memLimit() int64 {
+	if c.maxMemory > 0 {
+		return c.maxMemory
+	}
+	return maxBuf * threads
+}
+
+// Overwrites the file using the sequence of passes of the given
+// standard. An unknown standard makes the shred fail without touching
+// the file.
+func WithStandard(s Standard) Option {
+	return func(c *config) {
+		srcs, err := s.sources()
+		if err != nil {
+			c.err = err
+			return
+		}
+		c.sources = srcs
+	}
+}
+
+// Overwrites the file n times with random data. n must be at least 1.
+func WithPasses(n int) Option {
+	return func(c *config) {
+		if n < 1 {
+			c.err = errors.New("passes must be greater than 0")
+			return
+		}
+		c.sources = make([]PassSource, n)
+		for i := range c.sources {
+			c.sources[i] = RandomSource{}
+		}
+	}
+}
+
+// Overwrites the file once with each one of the given sources, in
+// order. At least one source must be given.
+func WithPassSources(srcs ...PassSource) Option {
+	return func(c *config) {
+		if len(srcs) == 0 {
+			c.err = errors.New("at least one pass source is needed")
+			return
+		}
+		c.sources = srcs
+	}
+}
+
+// Reads back every batch of the deterministic passes, those of a
+// ConstantSource, PatternSource or CounterSource, right after writing
+// it, and returns a *VerificationError, matching ErrVerificationFailed,
+// with the pass, offset and bytes of the first mismatch.
+// This catches storage that intermittently drops writes, which a
+// verification at the end of the pass could miss, at the cost of a
+// read for every write. Random passes are not read back. Note the read
+// may be served from the OS cache, see WithParanoidVerify.
+func WithReadAfterWrite(enabled bool) Option {
+	return func(c *config) {
+		c.readAfterWrite = enabled
+	}
+}
+
+// Removes the file even when overwriting it failed or was cancelled,
+// for when getting rid of the file matters more than wiping it. This
+// is a footgun: the error is still returned, but the file is gone, and
+// its content may be left on disk not or only partially overwritten,
+// out of reach of any retry. It only applies once the file was opened,
+// so files that are not regular, locked or refused for their hard links
+// are never removed. By default, files that failed are left in place.
+func WithForceRemoveOnError(enabled bool) Option {
+	return func(c *config) {
+		c.forceRemove = enabled
+	}
+}
+
+// Stops ShredAll and ShredDir from starting new files past the given
+// time, e.g. to fit a wipe in a maintenance window. Files in flight at
+// the deadline are completed, and the ones not started yet are reported
+// as skipped in the results, while no error is returned for them, so
+// ShredDir also keeps the directories holding them. It has no effect
+// when shreding a single file.
+func WithGlobalDeadline(t time.Time) Option {
+	return func(c *config) {
+		c.deadline = t
+	}
+}
+
+// Stops ShredAll and ShredDir from starting new files once the bytes
+// overwritten by the files completed so far reach n, counting every
+// pass, to bound the I/O of a wipe. Files in flight are completed, so
+// the budget can be exceeded by up to const workers files, and the ones
+// not started are reported as skipped, as with WithGlobalDeadline. 0,
+// the default, means no budget. It has no effect when shreding a
+// single file.
+func WithByteBudget(n int64) Option {
+	return func(c *config) {
+		if n < 0 {
+			c.err = errors.New("byte budget must not be negative")
+			return
+		}
+		c.budget = n
+	}
+}
+
+// Rounds the buffer size to a multiple of the block size of the
+// filesystem holding the file (usually 512 or 4096 bytes), so that every
+// write starts and ends on a block boundary, which avoids
+// read-modify-write cycles on the device. Only the last write of a file
+// whose size is not a multiple of the block size is shorter. The buffer
+// is rounded up, unless that breaks the limit of WithMaxMemory, and is
+// never smaller than one block, which takes precedence over the limit.
+// Off by default, keeping the sizes chosen by calcBuf.
+func WithSectorAlignedBuffer(enabled bool) Option {
+	return func(c *config) {
+		c.sectorAlign = enabled
+	}
+}
+
+// Marks files that are overwritten but kept, by Scrub or ShredFd, as
+// intentionally destroyed. After the last pass, the first bytes of the
+// file are overwritten with the 16 bytes "TATTER-SHREDDED\x00" followed
+// by the time of the shred in UTC as RFC 3339 (e.g.
+// "2006-01-02T15:04:05Z"), 36 bytes in total. Files are never grown,
+// so the marker is cut short on files smaller than that. It has no
+// effect on files that are removed.
+func WithSentinelMarker(enabled bool) Option {
+	return func(c *config) {
+		c.sentinel = enabled
+	}
+}
+
+// Runs the passes in a random order, drawn from crypto/rand anew for
+// every file, so the sequence of writes cannot be predicted by an
+// observer. It only matters for sequences of different patterns, such
+// as StandardDoD7 or custom ones given with WithPassSources; shuffling
+// identical random passes changes nothing. This does not materially
+// change the security of the wipe, and is only offered as defense in
+// depth.
+func WithShuffledPasses(enabled bool) Option {
+	return func(c *config) {
+		c.shuffle = enabled
+	}
+}
+
+// Sets a function that is called with every buffer after it has been
+// filled and right before it is written, along with the index of the
+// pass it belongs to. Each thread owns its buffer, so fn may be called
+// concurrently but never twice with the same slice at the same time.
+// This is a power-user hook: whatever fn leaves in buf is what ends up
+// on disk, so a misbehaving transform (e.g. one zeroing the buffer)
+// silently weakens the wipe.
+func WithBufferTransform(fn func(buf []byte, pass int)) Option {
+	return func(c *config) {
+		c.transform = fn
+	}
+}
+
+// Enables paranoid verification of the last pass. While writing it,
+// each thread hashes (SHA-256) the data it generates, and once the pass
+// is synced the file is read back and hashed again. A mismatch returns
+// ErrVerificationFailed, which proves the storage silently dropped or
+// altered writes. If the last pass is deterministic and there is no
+// buffer transform, the mismatch is located and detailed in a
+// *VerificationError. Note the read back may be served from the OS cache,
+// so only storage that lies about Sync is caught reliably.
+func WithParanoidVerify(enabled bool) Option {
+	return func(c *config) {
+		c.paranoid = enabled
+	}
+}
+
+// Enables diagnostics to detect writes coalesced or dropped by the OS.
+// After every pass the file is synced and the data written at a few
+// offsets spread over it is read back. If any of them does not hold
+// what the pass wrote, ErrPassNotPersisted is returned, which means the
+// passes did not all reach the storage and fewer overwrites than
+// requested may have happened. This costs a sync per pass.
+func WithPassDiagnostics(enabled bool) Option {
+	return func(c *config) {
+		c.diagnostics = enabled
+	}
+}
+
+// Locks the file exclusively (flock on Unix, LockFileEx on Windows)
+// before overwriting it and holds the lock until it is removed, so that
+// processes honoring the lock cannot append to or truncate it meanwhile.
+// If another process holds a lock, ErrFileLocked is returned and the
+// file is left untouched. Where locking is unsupported, either by the
+// platform or the filesystem, the shred goes on without a lock.
+func WithLock(enabled bool) Option {
+	return func(c *config) {
+		c.lock = enabled
+	}
+}
+
+// Makes ShredDir leave hidden entries and known system files untouched,
+// reporting them as skipped. Hidden entries are those whose name starts
+// with a dot on every platform, plus those with the hidden or system
+// attribute on Windows. Known system files are .DS_Store, .localized,
+// Thumbs.db, ehthumbs.db, desktop.ini and $RECYCLE.BIN. Hidden
+// directories, such as .git, are skipped with all their content. The
+// root given to ShredDir is never skipped.
+func WithSkipHidden(enabled bool) Option {
+	return func(c *config) {
+		c.skipHidden = enabled
+	}
+}
+
+// Makes the random passes that have no reader of their own, such as
+// those of the standards, read from r instead of crypto/rand. r is read
+// concurrently by the threads of a pass, so it must be safe for
+// concurrent use, as *os.File is.
+func WithRandSource(r io.Reader) Option {
+	return func(c *config) {
+		c.rand = r
+	}
+}
+
+// Makes random passes copy their data from the file at path, a pool of
+// random data generated once, e.g. with dd from /dev/urandom, instead
+// of reading fresh randomness, which is faster when wiping many files:
+// each batch is read from the pool with ReadAt at the offset it is
+// written to, wrapping around at the end of the pool if it is smaller
+// than the file. Every pass reads at a different shift, so passes do
+// not repeat each other, but the same data is written to every file,
+// and repeated within files larger than the pool, so anyone holding
+// the pool, or several shreded files, can tell them apart from fresh
+// random data. It is a performance trade, only meant for data that must
+// be unreadable, not for hiding that it was shreded. It takes precedence
+// over WithRandSource and WithRandFile. The file is opened for each
+// file to shred, and must not be empty.
+func WithRandPoolFile(path string) Option {
+	return func(c *config) {
+		c.poolFile = path
+	}
+}
+
+// Checks the source of WithRandSource or WithRandFile before every file
+// is shreded, reading a sample of 20000 bits from it, and refuses with
+// ErrWeakRandomSource sources whose sample is a single repeated byte or
+// fails the FIPS 140-2 monobit test, holding too many or too few ones.
+// It only catches broken sources, such as one returning zeros, not
+// predictable ones. crypto/rand, the default, is never checked.
+func WithRandCheck(enabled bool) Option {
+	return func(c *config) {
+		c.randCheck = enabled
+	}
+}
+
+// Same as WithRandSource, reading from the file at path, e.g. a given
+// entropy device such as /dev/urandom, or a file of pregenerated random
+// data, which must be large enough for every random pass. The file is
+// opened for each file to shred, before anything is written, so a file
+// that cannot be read fails the shred up front, and closed once done.
+func WithRandFile(path string) Option {
+	return func(c *config) {
+		c.randFile = path
+	}
+}
+
+// Makes the removal of the file durable before returning, ordering it
+// after the overwrite with explicit barriers:
+//  1. fsync of the file, so every pass reaches the disk;
+//  2. fsync of its directory;
+//  3. the renames of WithRename, each one followed by an fsync of the
+//     directory, as usual;
+//  4. unlink of the file;
+//  5. fsync of the directory, so the unlink reaches the disk.
+//
+// A crash at any point then leaves either the overwritten file or no
+// file at all. On Windows, where directories cannot be synced, only the
+// file is. Before overwriting, the filesystem is checked to honor the
+// syncs: on tmpfs and ramfs, which are volatile, and on nfs, cifs, smb2
+// and fuse, which are remote, a warning is logged, or the file is
+// refused with WithStrictDurability.
+func WithDurableRemove(enabled bool) Option {
+	return func(c *config) {
+		c.durableRemove = enabled
+	}
+}
+
+// Makes WithDurableRemove refuse files on filesystems that cannot make
+// their removal durable with ErrDurabilityUnsupported, before
+// overwriting them, instead of only warning about them.
+func WithStrictDurability(enabled bool) Option {
+	return func(c *config) {
+		c.strictDurable = enabled
+	}
+}
+
+// Chooses when the overwritten data is synced, trading durability for
+// speed. With SyncPerPass, the default, every pass is synced before the
+// next one starts, so each of them reaches the disk instead of being
+// coalesced in the page cache with the next one. SyncAtEnd syncs once
+// after the last pass, before the file is removed, so only that pass
+// is sure to be written to the disk. With SyncNever the file may even
+// be removed before its overwritten data is written back, and the OS
+// may then drop it altogether, leaving the original data on the disk:
+// only use it when the storage is wiped some other way. Options that
+// need the data on the disk, such as WithPassDiagnostics,
+// WithParanoidVerify or WithDurableRemove, still sync it.
+func WithSyncPolicy(policy SyncPolicy) Option {
+	return func(c *config) {
+		if policy < SyncPerPass || policy > SyncNever {
+			c.err = fmt.Errorf("invalid sync policy %d", policy)
+			return
+		}
+		c.syncPolicy = policy
+	}
+}
+
+// Calls fn after every write of the overwrite with its offset, the bytes
+// written and how long the write took, to profile where the time goes
+// on a slow device. Writes are timed one by one, which adds the cost of
+// reading the clock twice and calling fn to each of them. fn is called
+// concurrently by the threads writing the file, and by every file being
+// shreded at once, so it must be safe for concurrent use and return
+// quickly, since the writes wait for it.
+func WithWriteTrace(fn func(off, n int64, d time.Duration)) Option {
+	return func(c *config) {
+		c.trace = fn
+	}
+}
+
+// Leaves a decoy in place of every file instead of removing it: the
+// file keeps its name and size, holds the random data of the last pass,
+// and gets back its original access and modification times, so it looks
+// like a file of random data nobody touched, such as an encrypted
+// container or a key. This is meant to deny that anything was shreded
+// to someone examining the files later, not to hide it from someone
+// watching the filesystem as it happens. The change time, which cannot
+// be set, still tells when the file was written, as may filesystem
+// journals and backups. The last pass must be random, as must be every
+// pass if they are shuffled, and the file cannot carry a sentinel.
+func WithDecoy(enabled bool) Option {
+	return func(c *config) {
+		c.decoy = enabled
+	}
+}
+
+// Adds a first pass that reads every batch of the file and writes it
+// back XORed with fresh random data, so the data written depends on the
+// content being destroyed. It reads the whole file, so it is slower
+// than a random pass, and gives no real additional security over one,
+// since random data XORed with anything is just as random: it is only
+// offered for policies that ask for content-dependent overwrites.
+func WithXorPass(enabled bool) Option {
+	return func(c *config) {
+		c.xorPass = enabled
+	}
+}
+
+// Makes ShredDir report its overall progress to fn: written is the
+// number of bytes overwritten so far over all files and passes, out of
+// total, the sizes of all the files times the number of passes. Before
+// shreding anything, a planning pass stats every file found by the
+// walk to sum the total, which costs a stat per file up front, and
+// reports it with written at 0. Then fn is called after every batch
+// written, by one goroutine at a time, so written never decreases. The
+// other shred functions ignore it.
+func WithDirProgress(fn func(written, total int64)) Option {
+	return func(c *config) {
+		c.dirProgress = fn
+	}
+}
+
+// Makes ShredDir write a manifest to path once it ends, listing every
+// path of its results, as described by ManifestEntry, in the given
+// format, so they are kept after the program exits. path must be out of
+// the tree being shreded, or ShredDir fails before walking it. The
+// manifest is written even if the shred failed or was cancelled, and
+// failing to write it does not undo anything: ErrManifestNotWritten is
+// joined to the error returned instead.
+func WithManifest(path string, format ManifestFormat) Option {
+	return func(c *config) {
+		if format != ManifestJSON && format != ManifestCSV {
+			c.err = fmt.Errorf("unknown manifest format %d", format)
+			return
+		}
+		c.manifest = path
+		c.manifestFormat = format
+	}
+}
+
+// Makes Scrub overwrite only the blocks of 64 KiB that changed since
+// the previous Scrub of the file, for files scrubbed in place again and
+// again that mostly grow, such as large stores of rotating secrets. The
+// hashes of the blocks left by every Scrub are saved in the sidecar
+// file at path, and the next Scrub hashes the blocks again and
+// overwrites those whose hash differs, or that are new, with all the
+// passes. Without the sidecar file, or with one of another block size,
+// the whole file is overwritten. This trades thoroughness for speed:
+// the blocks that did not change are left as the previous Scrub did,
+// although the whole file is still read to hash it, and anyone who can
+// write the sidecar file can make Scrub skip blocks. Any other shred
+// but Scrub refuses it.
+func WithChangedBlocksOnly(path string) Option {
+	return func(c *config) {
+		c.blockHashes = path
+	}
+}
+
+// Makes NewShredder measure how fast 1, 2 and 4 threads overwrite a
+// temporary file, and shred with the fastest count instead of const
+// threads, tuning it to the storage of the temporary directory. The
+// measure writes a few MiB per count, so it delays NewShredder, and is
+// only taken once per process, later Shredders reuse it. If it fails,
+// a warning is logged and const threads are kept. WithShardCount and
+// WithSequentialSingleThread take precedence, and the functions of the
+// package, as opposed to the methods of Shredder, ignore it.
+func WithAutoTuneThreads(enabled bool) Option {
+	return func(c *config) {
+		c.autoTune = enabled
+	}
+}
+
+// Splits every pass over a file into n contiguous shards instead of
+// const threads, each one written by its own goroutine, e.g. to keep
+// more writes in flight on fast NVMe storage. Shards are multiples of
+// the buffer size, except for the last one, which takes the remainder,
+// and small files get fewer shards, down to one. Each shard has its own
+// buffer, so memory grows with n unless capped with WithMaxMemory. n
+// must be between 1 and 256.
+func WithShardCount(n int) Option {
+	return func(c *config) {
+		if n < 1 || n > maxShards {
+			c.err = fmt.Errorf("shard count must be between 1 and %d, got %d", maxShards, n)
+			return
+		}
+		c.shards = n
+	}
+}
+
+// Overwrites n bytes of files that report a size of 0, as virtual files
+// such as those of procfs do, even though they hold data. Without it,
+// such files are probed by reading their first byte, and fail with
+// ErrUnknownSize if there is one, rather than being reported shreded
+// with their data intact. Whether writing to them overwrites anything
+// at all is up to whatever implements them, and files that are really
+// empty are grown to n bytes. Files reporting a size are not affected.
+func WithExplicitSize(n int64) Option {
+	return func(c *config) {
+		if n < 0 {
+			c.err = errors.New("explicit size must not be negative")
+			return
+		}
+		c.explicitSize = n
+	}
+}
+
+// Overwrites the first and last 64 KiB of every file with random data,
+// and syncs them, before the passes start, so the headers and footers
+// most formats need to be read are gone first. If the passes are then
+// interrupted, by a cancellation, a crash or a failure, the file is at
+// least unusable, although most of its data is still there. Files up to
+// 128 KiB are overwritten whole. The bytes are counted in
+// BytesOverwritten, but not as a pass.
+func WithQuickInvalidateFirst(enabled bool) Option {
+	return func(c *config) {
+		c.invalidate = enabled
+	}
+}
+
+// Shreds and removes files, and walks directories, through fsys instead
+// of the os package, e.g. to work on an in-memory filesystem. Options
+// needing the real filesystem, such as WithLock or WithRename, are
+// refused along with it, and alternate data streams are not shreded.
+// A nil fsys is the real filesystem.
+func WithFileSystem(fsys FileSystem) Option {
+	return func(c *config) {
+		c.fs = fsys
+	}
+}
+
+// Overwrites files with a single thread instead of const threads, so
+// every pass writes the file from start to end in ascending order. Each
+// thread always writes its own partition in ascending order, but
+// several threads make a spinning disk seek back and forth between
+// their partitions, so this is usually faster on HDDs, while SSDs,
+// which do not seek, benefit from the concurrency of the default.
+func WithSequentialSingleThread(enabled bool) Option {
+	return func(c *config) {
+		c.sequential = enabled
+	}
+}
+
+// Recreates files of up to 4 KiB once overwritten, before removing
+// them: each one is closed, which releases the lock of WithLock, then
+// a new file of zeros is created next to it, synced and renamed over
+// it, so its name leads to a new inode, and then removed as usual.
+// Small files may be stored inline, within their inode rather than in
+// data blocks, as ext4 does with the inline_data feature for files that
+// fit in the inode, or btrfs within its metadata for files up to 2 KiB
+// by default, and copies of that metadata, such as those of the
+// journal, may not be reached by overwriting the file. Replacing the
+// inode makes the filesystem write a new one and drop the old one. It
+// is best-effort: what happens to the old inode is up to the
+// filesystem, and if the new file cannot be created or renamed, a
+// warning is logged and the file is removed as it is.
+func WithRecreateSmall(enabled bool) Option {
+	return func(c *config) {
+		c.recreate = enabled
+	}
+}
+
+// Discards the free blocks of the filesystem once files are removed,
+// issuing FITRIM on it as fstrim does, so SSDs and thin provisioned
+// volumes not mounted with discard are told the blocks the files held
+// are free and may erase them. FITRIM covers all the free space of the
+// filesystem, not just the blocks of the removed files, so it takes as
+// long as fstrim and ShredDir issues it once, after removing the tree,
+// rather than once per file. Only supported on Linux and for root, as
+// it needs CAP_SYS_ADMIN; elsewhere, or on filesystems without discard
+// support, a warning is logged and nothing else happens.
+func WithTrim(enabled bool) Option {
+	return func(c *config) {
+		c.trim = enabled
+	}
+}
+
+// Calls fn with the path of each file once it is overwritten, by every
+// pass and along with its streams and attributes, and right before it
+// is renamed and removed, e.g. to check it holds nothing but zeros. If
+// fn returns an error, the file is left overwritten but not removed,
+// and the error is returned wrapped in ErrRemovalVetoed, which tells it
+// apart from a failure to shred. fn is called exactly once for every
+// file overwritten successfully, and never for files kept by Scrub or
+// that failed before, although ShredAll and ShredDir may call it for
+// several files at once.
+func WithPreRemoveHook(fn func(path string) error) Option {
+	return func(c *config) {
+		c.preRemove = fn
+	}
+}
+
+// Asks fn whether to shred each file, as rm -i does, once it passed
+// every check and right before it is overwritten, so no work is wasted
+// on files that are declined. fn is called once per file, and never
+// concurrently, even when shreding several files at once. If it returns
+// false the file is left untouched, and ErrNotConfirmed is returned, or
+// the file is reported as skipped by ShredAll and ShredDir.
+func WithConfirm(fn func(path string) bool) Option {
+	var mu sync.Mutex
+	return func(c *config) {
+		c.confirm = func(path string) bool {
+			mu.Lock()
+			defer mu.Unlock()
+			return fn(path)
+		}
+	}
+}
+
+// Makes ShredDir leave untouched the files modified within the given
+// duration, which are likely still in use, reporting them as skipped.
+// Filters apply in order: hidden entries are skipped first when
+// WithSkipHidden is set, then non regular files, and then recent ones,
+// so the result of a path tells the first filter it failed. Zero, the
+// default, shreds files regardless of their age.
+func WithMinAge(d time.Duration) Option {
+	return func(c *config) {
+		if d < 0 {
+			c.err = errors.New("min age must not be negative")
+			return
+		}
+		c.minAge = d
+	}
+}
+
+// Limits the memory used for buffers while shredding a single file to
+// n bytes. The buffers of the threads are shrunk to fit, which means
+// more write operations, and so a slower shred: when that happens,
+// BufferReduced is set in the stats and a warning is logged, only once
+// for every distinct reduction when shreding several files. A value of
+// 0 or less removes the limit.
+func WithMaxMemory(n int64) Option {
+	return func(c *config) {
+		c.maxMemory = n
+	}
+}
+
+// Makes random passes generate the data for the whole file at once, in
+// a single buffer the threads write their batches from, instead of each
+// thread reading from the random source for every batch. This trades
+// memory for fewer calls to the random source (e.g. getrandom syscalls)
+// on medium sized files: the buffer takes as much memory as the file
+// size, in place of the buffers of the threads. It is only used for
+// files that fit in the memory limit, which defaults to 192MiB and can
+// be set with WithMaxMemory; larger files are shreded as usual.
+func WithSharedRandomBuffer(enabled bool) Option {
+	return func(c *config) {
+		c.sharedRand = enabled
+	}
+}
+
+// Sets the maximum number of passes allowed, 100 by default, so that a
+// typo such as WithPasses(1000000) returns ErrTooManyPasses instead of
+// keeping the storage busy for days. The Gutmann standard, the longest
+// one, takes 35 passes. n must be at least 1.
+func WithMaxPasses(n int) Option {
+	return func(c *config) {
+		if n < 1 {
+			c.err = fmt.Errorf("invalid maximum number of passes %d", n)
+			return
+		}
+		c.maxPasses = n
+	}
+}
+
+// Runs the threads overwriting the file with the idle I/O priority
+// class (as ionice -c3 does), so that they only use the disk when no
+// other process needs it, which suits shreding in the background. It
+// mostly slows down reads and synced writes: buffered writes are
+// flushed by the kernel at its own priority. Only supported on Linux,
+// elsewhere it has no effect.
+func WithLowPriority(enabled bool) Option {
+	return func(c *config) {
+		c.lowPriority = enabled
+	}
+}
+
+// Saves the progress of the shred to a checkpoint file at path, so
+// that after the process is restarted, shreding the same file with the
+// same options resumes where it was: completed passes are skipped, and
+// the interrupted one goes on from the offset each thread got to. The
+// file is synced before every save, about every second and at the end
+// of every pass, so a checkpoint never claims more than what reached
+// the storage. The checkpoint records a hash of the passes and the file
+// size, and resuming with different ones returns ErrCheckpointMismatch.
+// It is removed once the last pass completes. A resumed pass skips the
+// paranoid verification and pass diagnostics, which need the whole
+// pass. It is meant for a single, huge file, and cannot be combined
+// with WithShuffledPasses, whose order is not saved.
+func WithCheckpoint(path string) Option {
+	return func(c *config) {
+		c.checkpoint = path
+	}
+}
+
+// Sets the maximum size of the files to shred, so that shreding a
+// larger one, which may take hours, returns ErrFileTooLarge along with
+// its size instead, before anything is written. Zero, the default,
+// means unlimited.
+func WithMaxFileSize(bytes int64) Option {
+	return func(c *config) {
+		if bytes < 0 {
+			c.err = errors.New("max file size must not be negative")
+			return
+		}
+		c.maxSize = bytes
+	}
+}
+
+// Overrides the safety guards that would otherwise refuse to shred,
+// such as the maximum number of passes or file size, or the check that
+// the file is not the running executable, which tools deleting
+// themselves on purpose need. Use with care.
+func WithForce(enabled bool) Option {
+	return func(c *config) {
+		c.force = enabled
+	}
+}
+
+// Scrubs the extended attributes of the file before removing it, since
+// they can hold sensitive metadata that survives overwriting the
+// content. On Linux values are overwritten with zeros and removed, on
+// macOS they are removed; elsewhere this does nothing. It is a best
+// effort: attributes the process cannot change, such as SELinux labels,
+// are silently left alone.
+func WithScrubXattrs(enabled bool) Option {
+	return func(c *config) {
+		c.xattrs = enabled
+	}
+}
+
+// Deallocates the blocks of the file once it has been overwritten and
+// before it is removed. On Linux this punches a hole over the whole
+// file (FALLOC_FL_PUNCH_HOLE), which for very large files on ext4 or
+// xfs is a quick way to release the blocks; elsewhere, or when the
+// filesystem does not support it, the file is truncated instead.
+func WithPunchHoles(enabled bool) Option {
+	return func(c *config) {
+		c.punchHoles = enabled
+	}
+}
+
+// Sizes the buffer so each pass issues about k write operations, as
+// ceil(size/k) bytes each with a smaller final one, instead of the size
+// derived count calcBuf produces. Useful for benchmarking and to match
+// device characteristics. The buffer is still capped to 64MiB and to
+// the memory limit, so large files may need more writes. k must be at
+// least 1.
+func WithWriteCount(k int) Option {
+	return func(c *config) {
+		if k < 1 {
+			c.err = errors.New("write count must be greater than 0")
+			return
+		}
+		c.writeCount = int64(k)
+	}
+}
+
+// Overwrites files found on an encrypted volume with the last pass
+// only, since without the key of the volume what is on the storage is
+// only ciphertext either way, and records the volume in the Encrypted
+// stat. On Linux, a file is on an encrypted volume when the device of
+// its filesystem is a dm-crypt device, as LUKS ones are, or is stacked
+// on one, such as an LVM volume over LUKS, as told by sysfs. This is a
+// heuristic with limits: filesystems reporting a virtual device, such as
+// btrfs, or spanning several, are never detected, nor is encryption done
+// by the filesystem, such as fscrypt, or by the drive itself. FileVault
+// and BitLocker are not detected on other platforms, where it has no
+// effect. One pass only protects the data while the key stays secret:
+// anyone holding it, or able to unlock the volume, can still read what
+// the single pass missed, such as the original blocks on copy-on-write
+// or wear-leveled storage.
+func WithEncryptionAware(enabled bool) Option {
+	return func(c *config) {
+		c.encAware = enabled
+	}
+}
+
+// Makes shred confirm the removal of every file, for filesystems where
+// it may take time to propagate, such as distributed ones: once
+// removed, its path is polled with Lstat polls times, waiting delay
+// before the first poll and twice as long before every next one, so 5
+// polls from 100ms wait 3.1s in total. It fails with
+// ErrRemovalNotConfirmed if the path is still there at the last poll,
+// or is there again after being gone. It is off by default, as on local
+// disks the removal is done once Remove returns and polling only adds
+// latency. polls must be at least 1, and delay greater than 0.
+func WithRemovalCheck(polls int, delay time.Duration) Option {
+	return func(c *config) {
+		if polls < 1 || delay <= 0 {
+			c.err = fmt.Errorf("removal check needs at least 1 poll and a positive delay, got %d and %v", polls, delay)
+			return
+		}
+		c.removalPolls = polls
+		c.removalDelay = delay
+	}
+}
+
+// Keys the keystream of every KeystreamSource and UniqueBlockSource
+// pass with id along with its random key, for policies that require the
+// data written to depend on the host that wrote it. id is an identifier
+// of the host, such as its name or machine id, from 1 to 64 bytes. It
+// is for attribution only, not a secret: the randomness of the data
+// comes from the random key, which is unchanged, so any id, even a
+// public one, keeps the passes as strong as without it. Since the key
+// is not kept, the data alone does not prove which host wrote it, so
+// the attribution is only as good as the records kept with it, such as
+// an audit log. Other sources are left as they are.
+func WithHostMix(id []byte) Option {
+	return func(c *config) {
+		if len(id) < 1 || len(id) > maxHostID {
+			c.err = fmt.Errorf("host id must be 1 to %d bytes, got %d", maxHostID, len(id))
+			return
+		}
+		c.hostID = append([]byte(nil), id...)
+	}
+}
+
+// Skips the passes entirely, leaving the content of the file as it is:
+// the file is only renamed to a random name, once unless WithRenameCount
+// says otherwise, and removed, as WithRename does, so only its name and
+// directory entry are hidden. Nothing is overwritten, so its data stays
+// on the storage until reused, which is no protection at all on hard
+// disks. It is only meant for SSDs with full disk encryption, or
+// copy-on-write filesystems, where overwriting would not reach the
+// original blocks anyway and the storage is trusted to discard them,
+// e.g. with TRIM. Alternate data streams are not overwritten either,
+// and Scrub and WithDecoy refuse it.
+func WithoutOverwrite(enabled bool) Option {
+	return func(c *config) {
+		c.noOverwrite = enabled
+	}
+}
+
+// Renames the file to a random name of the same length before removing
+// it, syncing the directory, so the original name does not survive in
+// the directory entry. The new name is taken with a hard link, so no
+// file created under it in the meantime is replaced, and the filesystem
+// must support them.
+func WithRename(enabled bool) Option {
+	return func(c *config) {
+		c.renames = 0
+		if enabled {
+			c.renames = 1
+		}
+	}
+}
+
+// Renames the file n times before removing it, syncing the directory
+// after every rename. The first random name keeps the length of the
+// original one and the following ones get shorter, down to a single
+// char, so the directory entry does not even keep the original length.
+// A count of 0 disables renaming.
+func WithRenameCount(n int) Option {
+	return func(c *config) {
+		if n < 0 {
+			c.err = errors.New("rename count must not be negative")
+			return
+		}
+		c.renames = n
+	}
+}
+
+// Makes WipeFreeSpace fill temporary files of up to n bytes each, one
+// after another, until the filesystem is full, instead of a single one
+// as large as the free space, which may exceed the maximum file size of
+// the filesystem, e.g. 4 GiB on FAT32, or a per-file quota. All of them
+// are kept until the filesystem is full, then removed. n must be
+// greater than 0, e.g. 1 GiB.
+func WithWipeFileSize(n int64) Option {
+	return func(c *config) {
+		if n <= 0 {
+			c.err = errors.New("wipe file size must be greater than 0")
+			return
+		}
+		c.wipeFileSize = n
+	}
+}
+
+// Renames the file to name, within its directory, before removing it,
+// syncing the directory, so anyone watching the directory sees it was
+// deleted on purpose, e.g. with "DELETED_BY_POLICY". If a file of that
+// name exists, -1, -2 and so on are appended to it until one is free,
+// so no other file is replaced: the name is taken with a hard link, so
+// the filesystem must support them. It is meant for transparency, and
+// defeats the purpose of WithRename, hiding the name and the removal of
+// the file, so both cannot be combined. name must be a file name, not a
+// path.
+func WithFinalName(name string) Option {
+	return func(c *config) {
+		if name != filepath.Base(name) || name == "." || name == ".." {
+			c.err = fmt.Errorf("invalid final name %q", name)
+			return
+		}
+		c.finalName = name
+	}
+}
+
+// Counts the other processes holding each file open before it is
+// overwritten, in OpenByOthers of the stats, and logs a warning if there
+// are any, since a reader would get the overwritten data mid-read. The
+// file is still shreded, so to defer it, check first with DryRun, whose
+// plan counts them too with this option, or with WithConfirm. It is a
+// best-effort advice, only available on Linux, where it scans the
+// descriptors of every process in /proc, so it is slow with many files
+// or processes, and misses the processes of other users unless running
+// as root, as well as files opened afterwards.
+func WithOpenCheck(enabled bool) Option {
+	return func(c *config) {
+		c.openCheck = enabled
+	}
+}
+
+// Makes ShredDir follow the symlinks it finds, shreding the files they
+// lead to and walking the directories they lead to, even outside of
+// root, so use it with care. Every file and directory is identified by
+// its device and inode, so each one is shreded or walked only once,
+// however many links lead to it, and symlink cycles end the walk there.
+// Files reached through a symlink are reported in the results by their
+// real path, with every symlink resolved, while the symlinks themselves
+// are kept and reported as skipped, as are the directories they lead
+// to, which are emptied but not removed.
+func WithFollowSymlinks(enabled bool) Option {
+	return func(c *config) {
+		c.followLinks = enabled
+	}
+}
+
+// Appends a record of every file shreded, or that failed to be, to the
+// audit log at path, created if needed, as described by AuditRecord.
+// Records are chained by hash, so VerifyAuditLog tells if the log was
+// edited afterwards. The log is synced after every record, is locked
+// while appending so several processes can share it, and is refused
+// when found among the files to shred. A shred whose record cannot be
+// appended fails, even though the file was shreded.
+func WithAuditLog(path string) Option {
+	return func(c *config) {
+		c.auditLog = path
+	}
+}
+
+// Refuses to shred files with more than one hard link, returning
+// ErrHardLinked before anything is written, unless WithForce is set.
+// Without it, such files are shreded and HardLinkCount in the stats
+// tells the file survives through its other names.
+func WithRefuseHardLinks(enabled bool) Option {
+	return func(c *config) {
+		c.refuseLinks = enabled
+	}
+}
+
+// Sets how failures are combined when several threads of a pass, or
+// several files of ShredAll and ShredDir, fail: JoinAll, the default,
+// returns all of them joined, for detailed auditing, while FirstError
+// returns just the first one, which is simpler to handle. Either way,
+// errors.Is and errors.As work against the sentinels and *ShredError.
+func WithErrorMode(mode ErrorMode) Option {
+	return func(c *config) {
+		if mode < JoinAll || mode > FirstError {
+			c.err = fmt.Errorf("invalid error mode %d", mode)
+			return
+		}
+		c.errorMode = mode
+	}
+}
@@ -0,0 +1,728 @@
+package tatter
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWithBufferTransform(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/transform.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/transform.bin")
+	defer f.Close()
+	var mu sync.Mutex
+	seen := make(map[int]bool)
+	c := newConfig([]Option{WithBufferTransform(func(buf []byte, pass int) {
+		for i := range buf {
+			buf[i] = 0xAA
+		}
+		mu.Lock()
+		seen[pass] = true
+		mu.Unlock()
+	})})
+	if err := shredFile(context.Background(), f, c, &ShredStats{}); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if len(seen) != 3 {
+		t.Fatalf("transform called on %d passes, want 3\n", len(seen))
+	}
+	b := make([]byte, 40716)
+	if _, err := f.ReadAt(b, 0); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	for i, v := range b {
+		if v != 0xAA {
+			t.Fatalf("byte %d is %#x, want 0xaa\n", i, v)
+		}
+	}
+}
+
+type countingReader struct {
+	mu    sync.Mutex
+	reads int
+}
+
+func (r *countingReader) Read(b []byte) (int, error) {
+	r.mu.Lock()
+	r.reads++
+	r.mu.Unlock()
+	return rand.Read(b)
+}
+
+type TestSharedRandTable struct {
+	name   string
+	opts   []Option
+	shared bool
+}
+
+func TestWithSharedRandomBuffer(t *testing.T) {
+	var tests = []TestSharedRandTable{
+		{"Disabled", nil, false},
+		{"Enabled", []Option{WithSharedRandomBuffer(true)}, true},
+		{"OverLimit", []Option{WithSharedRandomBuffer(true), WithMaxMemory(40000)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := copyFile(t, "testdata/extra.bin", "testdata/test/shared.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			defer os.Remove("testdata/test/shared.bin")
+			defer f.Close()
+			r := &countingReader{}
+			opts := append([]Option{WithPassSources(RandomSource{r}, RandomSource{r})}, tt.opts...)
+			if err := shredFile(context.Background(), f, newConfig(opts), &ShredStats{}); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			if tt.shared != (r.reads == 2) {
+				t.Fatalf("got %d reads from the random source\n", r.reads)
+			}
+			if patternIn(t, "Extra123/", f) {
+				t.Fatalf("pattern found in file\n")
+			}
+		})
+	}
+}
+
+func TestWithMaxMemory(t *testing.T) {
+	c := newConfig([]Option{WithMaxMemory(3000)})
+	if got := c.bufSize(1 << 30); got != 1000 {
+		t.Fatalf("expected 1000, got %d\n", got)
+	}
+	c = newConfig([]Option{WithMaxMemory(1)})
+	if got := c.bufSize(1 << 30); got != 1 {
+		t.Fatalf("expected 1, got %d\n", got)
+	}
+	if got := newConfig(nil).bufSize(1 << 30); got != calcBuf(1<<30) {
+		t.Fatalf("expected %d, got %d\n", calcBuf(1<<30), got)
+	}
+}
+
+type TestBufferReducedTable struct {
+	name      string
+	maxMemory int64
+	want      bool
+}
+
+func TestBufferReduced(t *testing.T) {
+	var tests = []TestBufferReducedTable{
+		{"Unlimited", 0, false},
+		{"Roomy", 1 << 20, false},
+		{"Reduced", 300, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var paths []string
+			for i := 0; i < 3; i++ {
+				path := fmt.Sprintf("testdata/test/reduced%d.bin", i)
+				f, err := copyFile(t, "testdata/large.bin", path)
+				if err != nil {
+					t.Fatalf("err: %v\n", err)
+				}
+				f.Close()
+				defer os.Remove(path)
+				paths = append(paths, path)
+			}
+			l := &testLogger{}
+			results, err := ShredAll(paths, WithMaxMemory(tt.maxMemory), WithLogger(l))
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			for path, r := range results {
+				if r.Stats.BufferReduced != tt.want {
+					t.Fatalf("%s: expected BufferReduced %v\n", path, tt.want)
+				}
+			}
+			// Every file is reduced the same way, so it is warned once.
+			if warns := len(l.msgs[LevelWarn]); (warns == 1) != tt.want || warns > 1 {
+				t.Fatalf("expected a single warning if reduced, got %q\n", l.msgs[LevelWarn])
+			}
+		})
+	}
+}
+
+type TestPassesTable struct {
+	name string
+	opts []Option
+	want error
+}
+
+func TestWithMaxPasses(t *testing.T) {
+	var tests = []TestPassesTable{
+		{"Default", nil, nil},
+		{"Gutmann", []Option{WithStandard(StandardGutmann)}, nil},
+		{"Typo", []Option{WithPasses(1000000)}, ErrTooManyPasses},
+		{"Lowered", []Option{WithMaxPasses(2)}, ErrTooManyPasses},
+		{"Raised", []Option{WithPasses(150), WithMaxPasses(150)}, nil},
+		{"Forced", []Option{WithPasses(1000000), WithForce(true)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := newConfig(tt.opts).validate(); !errors.Is(err, tt.want) {
+				t.Fatalf("got: %v, want %v\n", err, tt.want)
+			}
+		})
+	}
+	for _, n := range []int{0, -1} {
+		if err := newConfig([]Option{WithMaxPasses(n)}).validate(); err == nil {
+			t.Fatalf("expected a maximum of %d passes refused\n", n)
+		}
+	}
+}
+
+func TestWithMaxFileSize(t *testing.T) {
+	var tests = []TestPassesTable{
+		{"Default", nil, nil},
+		{"Larger", []Option{WithMaxFileSize(100)}, ErrFileTooLarge},
+		{"Exact", []Option{WithMaxFileSize(3150)}, nil},
+		{"Forced", []Option{WithMaxFileSize(100), WithForce(true)}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := copyFile(t, "testdata/large.bin", "testdata/test/maxsize.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			f.Close()
+			defer os.Remove("testdata/test/maxsize.bin")
+			err = Shred("testdata/test/maxsize.bin", tt.opts...)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got: %v, want %v\n", err, tt.want)
+			}
+			if err != nil && !strings.Contains(err.Error(), "3150 bytes") {
+				t.Fatalf("expected the size in %q\n", err)
+			}
+		})
+	}
+	if err := newConfig([]Option{WithMaxFileSize(-1)}).validate(); err == nil {
+		t.Fatalf("expected negative size err, got nil\n")
+	}
+}
+
+func TestWithPasses(t *testing.T) {
+	if n := len(newConfig([]Option{WithPasses(7)}).sources); n != 7 {
+		t.Fatalf("expected 7 passes, got %d\n", n)
+	}
+	if err := newConfig([]Option{WithPasses(0)}).validate(); err == nil {
+		t.Fatalf("expected err, got nil\n")
+	}
+}
+
+type TestWriteCountTable struct {
+	name       string
+	size       int64
+	k          int
+	want       int64
+	wantWrites int
+}
+
+func TestWithWriteCount(t *testing.T) {
+	var tests = []TestWriteCountTable{
+		{"Exact", 100, 4, 25, 4},
+		{"Remainder", 101, 4, 26, 4},
+		{"MoreWritesThanBytes", 3, 10, 1, 3},
+		{"Clamped", 10 * maxBuf, 2, maxBuf, 10},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newConfig([]Option{WithWriteCount(tt.k)}).bufSize(tt.size)
+			if got != tt.want {
+				t.Fatalf("expected %d, got %d\n", tt.want, got)
+			}
+			if writes := int((tt.size + got - 1) / got); writes != tt.wantWrites {
+				t.Fatalf("expected %d writes, got %d\n", tt.wantWrites, writes)
+			}
+		})
+	}
+	if err := newConfig([]Option{WithWriteCount(0)}).validate(); err == nil {
+		t.Fatalf("expected err, got nil\n")
+	}
+}
+
+func TestWithWriteCountShred(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/writes.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/writes.bin")
+	defer f.Close()
+	var mu sync.Mutex
+	var sizes []int
+	count := WithBufferTransform(func(buf []byte, pass int) {
+		mu.Lock()
+		sizes = append(sizes, len(buf))
+		mu.Unlock()
+	})
+	c := newConfig([]Option{WithPasses(1), WithWriteCount(7), count})
+	if err := shredFile(context.Background(), f, c, &ShredStats{}); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	total := 0
+	for _, n := range sizes {
+		total += n
+	}
+	if len(sizes) != 7 || total != 40716 {
+		t.Fatalf("got %d writes of %d bytes, want 7 of 40716\n", len(sizes), total)
+	}
+}
+
+type TestErrorModeTable struct {
+	name   string
+	mode   ErrorMode
+	joined bool
+}
+
+func TestWithErrorMode(t *testing.T) {
+	var tests = []TestErrorModeTable{
+		{"JoinAll", JoinAll, true},
+		{"FirstError", FirstError, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := createFailing(t, "testdata/test/errmode.bin")
+			if err != nil {
+				t.Fatalf("failing file not created")
+			}
+			defer f.Close()
+			c := newConfig([]Option{WithErrorMode(tt.mode), WithWriteCount(8)})
+			err = shredFile(context.Background(), f, c, &ShredStats{})
+			var serr *ShredError
+			if !errors.As(err, &serr) {
+				t.Fatalf("got: %v, want *ShredError\n", err)
+			}
+			n := 0
+			eachShredError(err, func(*ShredError) { n++ })
+			if tt.joined != (n == threads) || (!tt.joined && n != 1) {
+				t.Fatalf("got %d errors\n", n)
+			}
+			paths := []string{"testdata/test/nonexistent1", "testdata/test/nonexistent2"}
+			_, err = ShredAll(paths, WithErrorMode(tt.mode))
+			if !errors.Is(err, fs.ErrNotExist) {
+				t.Fatalf("got: %v, want %v\n", err, fs.ErrNotExist)
+			}
+			if _, ok := err.(interface{ Unwrap() []error }); ok != tt.joined {
+				t.Fatalf("unexpected error %v\n", err)
+			}
+		})
+	}
+	if err := newConfig([]Option{WithErrorMode(2)}).validate(); err == nil {
+		t.Fatalf("expected an invalid error mode refused\n")
+	}
+}
+
+func TestWithShuffledPasses(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/shuffle.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/shuffle.bin")
+	defer f.Close()
+	var mu sync.Mutex
+	order := make(map[int]byte)
+	c := newConfig([]Option{
+		WithPassSources(ConstantSource(1), ConstantSource(2), ConstantSource(3)),
+		WithShuffledPasses(true),
+		WithBufferTransform(func(buf []byte, pass int) {
+			mu.Lock()
+			order[pass] = buf[0]
+			mu.Unlock()
+		}),
+	})
+	if err := shredFile(context.Background(), f, c, &ShredStats{}); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	seen := make(map[byte]bool)
+	for _, v := range order {
+		seen[v] = true
+	}
+	if len(order) != 3 || len(seen) != 3 {
+		t.Fatalf("expected every pattern once, got %v\n", order)
+	}
+	b := make([]byte, 1)
+	if _, err := f.ReadAt(b, 0); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if b[0] != order[2] {
+		t.Fatalf("expected the last pass %#x on disk, got %#x\n", order[2], b[0])
+	}
+}
+
+func TestWithReadAfterWrite(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/raw.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/raw.bin")
+	defer f.Close()
+	c := newConfig([]Option{WithStandard(StandardDoD3), WithReadAfterWrite(true)})
+	if err := shredFile(context.Background(), f, c, &ShredStats{}); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+}
+
+type TestAlignBufTable struct {
+	name      string
+	maxMemory int64
+	bufSize   int64
+	block     int64
+	want      int64
+}
+
+func TestAlignBuf(t *testing.T) {
+	var tests = []TestAlignBufTable{
+		{"Aligned", 0, 8192, 4096, 8192},
+		{"Up", 0, 5000, 4096, 8192},
+		{"Small", 0, 100, 512, 512},
+		{"NoBlock", 0, 5000, 0, 5000},
+		{"DownForMemory", 3 * 6000, 6000, 4096, 4096},
+		{"OneBlockOverMemory", 3 * 100, 100, 4096, 4096},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConfig(nil)
+			c.maxMemory = tt.maxMemory
+			if got := c.alignBuf(tt.bufSize, tt.block); got != tt.want {
+				t.Fatalf("expected %d, got %d\n", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestWithSectorAlignedBuffer(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/aligned.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/aligned.bin")
+	defer f.Close()
+	block := blockSize(f)
+	var mu sync.Mutex
+	var short int
+	c := newConfig([]Option{WithStandard(StandardQuick), WithSectorAlignedBuffer(true), WithBufferTransform(func(buf []byte, pass int) {
+		mu.Lock()
+		if int64(len(buf))%block != 0 {
+			short++
+		}
+		mu.Unlock()
+	})})
+	if err := shredFile(context.Background(), f, c, &ShredStats{}); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if 40716%block != 0 && short != 1 {
+		t.Fatalf("expected only the last write unaligned, got %d\n", short)
+	}
+}
+
+func TestWithRandFile(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/randfile.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/randfile.bin")
+	// extra.bin holds enough data for the 3 passes of large.bin.
+	if err := Scrub("testdata/test/randfile.bin", WithRandFile("testdata/extra.bin"), WithSequentialSingleThread(true)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	b, err := os.ReadFile("testdata/test/randfile.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	extra, err := os.ReadFile("testdata/extra.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	// A single thread reads the file in order, so the last pass wrote
+	// its third chunk.
+	if !bytes.Equal(b, extra[2*3150:3*3150]) {
+		t.Fatalf("expected the last pass read from the rand file\n")
+	}
+	err = Shred("testdata/test/randfile.bin", WithRandFile("testdata/test/missing.bin"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("expected a missing rand file err, got %v\n", err)
+	}
+	if _, err := os.Stat("testdata/test/randfile.bin"); err != nil {
+		t.Fatalf("expected the file untouched, got %v\n", err)
+	}
+}
+
+type TestSyncPolicyTable struct {
+	name   string
+	policy SyncPolicy
+	want   []bool
+}
+
+func TestWithSyncPolicy(t *testing.T) {
+	var tests = []TestSyncPolicyTable{
+		{"PerPass", SyncPerPass, []bool{true, true, true}},
+		{"AtEnd", SyncAtEnd, []bool{false, false, true}},
+		{"Never", SyncNever, []bool{false, false, false}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConfig([]Option{WithSyncPolicy(tt.policy)})
+			for pass, want := range tt.want {
+				if got := c.syncs(pass, len(tt.want)); got != want {
+					t.Fatalf("pass %d: got %v, want %v\n", pass, got, want)
+				}
+			}
+			f, err := copyFile(t, "testdata/large.bin", "testdata/test/syncpolicy.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			f.Close()
+			defer os.Remove("testdata/test/syncpolicy.bin")
+			if err := Shred("testdata/test/syncpolicy.bin", WithSyncPolicy(tt.policy)); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+		})
+	}
+	if err := newConfig([]Option{WithSyncPolicy(3)}).validate(); err == nil {
+		t.Fatalf("expected invalid policy err, got nil\n")
+	}
+}
+
+func TestWithWriteTrace(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/trace.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/trace.bin")
+	defer f.Close()
+	var mu sync.Mutex
+	var writes int
+	var total int64
+	trace := func(off, n int64, d time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+		if d < 0 {
+			t.Errorf("negative duration %v at offset %d\n", d, off)
+		}
+		writes++
+		total += n
+	}
+	c := newConfig([]Option{WithWriteTrace(trace), WithWriteCount(10), WithStandard(StandardQuick)})
+	if err := shredFile(context.Background(), f, c, &ShredStats{}); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if writes < 10 || total != 40716 {
+		t.Fatalf("expected 40716 bytes in 10 writes or more, got %d in %d\n", total, writes)
+	}
+}
+
+type TestDecoyTable struct {
+	name  string
+	opts  []Option
+	valid bool
+}
+
+func TestWithDecoyValidate(t *testing.T) {
+	var tests = []TestDecoyTable{
+		{"Default", nil, true},
+		{"DoD3", []Option{WithStandard(StandardDoD3)}, true},
+		{"Quick", []Option{WithStandard(StandardQuick)}, false},
+		{"Sentinel", []Option{WithSentinelMarker(true)}, false},
+		{"Shuffled", []Option{WithStandard(StandardDoD3), WithShuffledPasses(true)}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := newConfig(append(tt.opts, WithDecoy(true))).validate()
+			if (err == nil) != tt.valid {
+				t.Fatalf("got: %v, want valid %v\n", err, tt.valid)
+			}
+		})
+	}
+}
+
+func TestWithXorPass(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/xor.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/xor.bin")
+	defer f.Close()
+	old, err := os.ReadFile("testdata/extra.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	// With an all zeros source of randomness, the XOR pass writes the
+	// file back as it was, and the transform sees exactly that.
+	var xored []byte
+	var mu sync.Mutex
+	keep := func(buf []byte, pass int) {
+		mu.Lock()
+		defer mu.Unlock()
+		if pass == 0 {
+			xored = append(xored, buf...)
+		}
+	}
+	c := newConfig([]Option{WithStandard(StandardQuick), WithXorPass(true), WithRandSource(bytes.NewReader(make([]byte, 40716))), WithSequentialSingleThread(true), WithBufferTransform(keep)})
+	stats := &ShredStats{}
+	if err := shredFile(context.Background(), f, c, stats); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if stats.Passes != 2 || !bytes.Equal(xored, old) {
+		t.Fatalf("expected 2 passes and the original content XORed with zeros, got %d passes\n", stats.Passes)
+	}
+}
+
+func TestWithRandPoolFile(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/pooled.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/pooled.bin")
+	if err := Scrub("testdata/test/pooled.bin", WithPasses(2), WithRandPoolFile("testdata/small.bin")); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	pool, err := os.ReadFile("testdata/small.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	b, err := os.ReadFile("testdata/test/pooled.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	// The last pass reads the pool shifted once.
+	for i, v := range b {
+		if want := pool[(int64(i)+poolShift)%int64(len(pool))]; v != want {
+			t.Fatalf("byte %d is %#x, want %#x\n", i, v, want)
+		}
+	}
+	if err := Shred("testdata/test/pooled.bin", WithRandPoolFile("testdata/test/nopool.bin")); err == nil {
+		t.Fatalf("expected missing pool err, got nil\n")
+	}
+	if err := Shred("testdata/test/pooled.bin", WithRandPoolFile("testdata/empty.bin")); err == nil {
+		t.Fatalf("expected empty pool err, got nil\n")
+	}
+}
+
+func TestWithRandCheck(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/randcheck.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/randcheck.bin")
+	zeros := bytes.NewReader(make([]byte, 1<<16))
+	if err := Shred("testdata/test/randcheck.bin", WithRandSource(zeros), WithRandCheck(true)); !errors.Is(err, ErrWeakRandomSource) {
+		t.Fatalf("expected ErrWeakRandomSource, got %v\n", err)
+	}
+	if err := Shred("testdata/test/randcheck.bin", WithRandCheck(true)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+}
+
+type TestShardCountTable struct {
+	name  string
+	n     int
+	size  int64
+	want  int
+	valid bool
+}
+
+func TestWithShardCount(t *testing.T) {
+	var tests = []TestShardCountTable{
+		{"Zero", 0, 40716, 0, false},
+		{"TooMany", maxShards + 1, 40716, 0, false},
+		{"One", 1, 40716, 1, true},
+		{"Seven", 7, 40716, 7, true},
+		{"SmallFile", 16, 10, 10, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := newConfig([]Option{WithShardCount(tt.n), WithWriteCount(100)})
+			if err := c.validate(); (err == nil) != tt.valid {
+				t.Fatalf("got: %v, want valid %v\n", err, tt.valid)
+			}
+			if !tt.valid {
+				return
+			}
+			bounds := partition(tt.size, c.bufSize(tt.size), c.nthreads())
+			if len(bounds)-1 != tt.want || bounds[len(bounds)-1] != tt.size {
+				t.Fatalf("expected %d shards up to %d, got %v\n", tt.want, tt.size, bounds)
+			}
+		})
+	}
+	if err := newConfig([]Option{WithShardCount(2), WithSequentialSingleThread(true)}).validate(); err == nil {
+		t.Fatalf("expected err, got nil\n")
+	}
+}
+
+// Measures how writing a file scales with the number of shards. The
+// page cache hides the device, so run it with a file on fast storage.
+func BenchmarkShardCount(b *testing.B) {
+	for _, n := range []int{1, 2, 4, 8, 16} {
+		b.Run(fmt.Sprintf("%d", n), func(b *testing.B) {
+			f, err := os.Create("testdata/test/bench.bin")
+			if err != nil {
+				b.Fatalf("err: %v\n", err)
+			}
+			defer os.Remove("testdata/test/bench.bin")
+			defer f.Close()
+			if err := f.Truncate(1 << 26); err != nil {
+				b.Fatalf("err: %v\n", err)
+			}
+			c := newConfig([]Option{WithPasses(1), WithShardCount(n), WithSyncPolicy(SyncNever)})
+			b.SetBytes(1 << 26)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := shredFile(context.Background(), f, c, &ShredStats{}); err != nil {
+					b.Fatalf("err: %v\n", err)
+				}
+			}
+		})
+	}
+}
+
+type TestWithEncryptionAwareTable struct {
+	name   string
+	volume string
+	opts   []Option
+	passes int
+}
+
+func TestWithEncryptionAware(t *testing.T) {
+	var tests = []TestWithEncryptionAwareTable{
+		{"Encrypted", "dm-crypt luks-root", []Option{WithEncryptionAware(true)}, 1},
+		{"Plain", "", []Option{WithEncryptionAware(true)}, 3},
+		{"Disabled", "dm-crypt luks-root", nil, 3},
+	}
+	defer func() { encryptedVolume = cryptVolume }()
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			encryptedVolume = func(os.FileInfo) string { return tt.volume }
+			f, err := copyFile(t, "testdata/small.bin", "testdata/test/encrypted.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			f.Close()
+			stats, err := ShredWithStats("testdata/test/encrypted.bin", tt.opts...)
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			if stats.Passes != tt.passes {
+				t.Fatalf("expected %d passes, got %d\n", tt.passes, stats.Passes)
+			}
+			want := tt.volume
+			if tt.opts == nil {
+				want = "" // not even looked for
+			}
+			if stats.Encrypted != want {
+				t.Fatalf("expected volume %q, got %q\n", want, stats.Encrypted)
+			}
+		})
+	}
+}
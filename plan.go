@@ -0,0 +1,93 @@
+package tatter
+
+import (
+	"crypto/aes"
+	"fmt"
+)
+
+// What shreding a file would do, as reported by DryRun. Bytes is what
+// all passes would write, and RandomBytes the randomness they would
+// consume.
+type Plan struct {
+	Path        string
+	Size        int64
+	Passes      int
+	Bytes       int64
+	RandomBytes int64
+	// Other processes holding the file open, only with WithOpenCheck.
+	OpenByOthers int
+}
+
+// Returns the randomness consumed by a pass of src over size bytes.
+// Random and XOR passes read a byte of randomness for every byte
+// written, while keystream passes only read their key and IV, and
+// unique block passes a nonce too. Constant, pattern and counter
+// passes, like custom sources, are taken to consume none.
+func randomBytes(src PassSource, size int64) int64 {
+	switch s := src.(type) {
+	case RandomSource, xorSource:
+		return size
+	case KeystreamSource:
+		return 32 + aes.BlockSize
+	case UniqueBlockSource:
+		return 32 + aes.BlockSize + 8
+	case MixSource:
+		return size - int64(float64(size)*s.Ratio) // on average
+	}
+	return 0
+}
+
+// Reports what shreding the file at path with the given options would
+// do, without opening it. The options and the file go through the same
+// checks Shred runs before opening it, so an error here means Shred
+// would refuse the file too, although Shred may still fail on checks
+// that need the file open.
+func DryRun(path string, opts ...Option) (Plan, error) {
+	c := newConfig(opts)
+	sources := c.sources
+	if c.xorPass {
+		sources = append([]PassSource{xorSource{}}, sources...)
+	}
+	p := Plan{Path: path, Passes: len(sources)}
+	if err := c.validate(); err != nil {
+		return p, err
+	}
+	info, err := c.fsys().Lstat(path)
+	if err != nil {
+		return p, err
+	}
+	if !info.Mode().IsRegular() {
+		return p, fmt.Errorf("%w: %s is %v", ErrNotRegularFile, path, info.Mode().Type())
+	}
+	if err := c.checkSelf(path, info); err != nil {
+		return p, err
+	}
+	if err := c.checkAuditLog(path, info); err != nil {
+		return p, err
+	}
+	p.Size = info.Size()
+	if p.Size == 0 {
+		p.Size = c.explicitSize
+	}
+	if err := c.checkSize(path, p.Size); err != nil {
+		return p, err
+	}
+	if c.openCheck {
+		p.OpenByOthers = openByOthers(info)
+	}
+	p.Bytes = p.Size * int64(p.Passes)
+	for _, src := range sources {
+		p.RandomBytes += randomBytes(src, p.Size)
+	}
+	return p, nil
+}
+
+// Reports whether Shred would refuse the file at path with the given
+// options, returning the first refusal, such as ErrNotRegularFile for
+// symlinks, ErrSelfShred or ErrFileTooLarge, or nil if it would go on,
+// e.g. so a UI can disable the action and tell why. It runs the checks
+// of DryRun only, without opening, writing or locking anything.
+func CanShred(path string, opts ...Option) error {
+	_, err := DryRun(path, opts...)
+	return err
+}
@@ -0,0 +1,91 @@
+package tatter
+
+import (
+	"bytes"
+	"errors"
+	"io/fs"
+	"os"
+	"testing"
+)
+
+type TestDryRunTable struct {
+	name string
+	opts []Option
+	want Plan
+}
+
+func TestDryRun(t *testing.T) {
+	var tests = []TestDryRunTable{
+		{"Default", nil, Plan{Path: "testdata/large.bin", Size: 3150, Passes: 3, Bytes: 3 * 3150, RandomBytes: 3 * 3150}},
+		{"Quick", []Option{WithStandard(StandardQuick)}, Plan{Path: "testdata/large.bin", Size: 3150, Passes: 1, Bytes: 3150}},
+		{"DoD3", []Option{WithStandard(StandardDoD3)}, Plan{Path: "testdata/large.bin", Size: 3150, Passes: 3, Bytes: 3 * 3150, RandomBytes: 3150}},
+		{"Keystream", []Option{WithPassSources(KeystreamSource{}, ConstantSource(0))}, Plan{Path: "testdata/large.bin", Size: 3150, Passes: 2, Bytes: 2 * 3150, RandomBytes: 48}},
+		{"Xor", []Option{WithStandard(StandardQuick), WithXorPass(true)}, Plan{Path: "testdata/large.bin", Size: 3150, Passes: 2, Bytes: 2 * 3150, RandomBytes: 3150}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p, err := DryRun("testdata/large.bin", tt.opts...)
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			if p != tt.want {
+				t.Fatalf("expected %+v, got %+v\n", tt.want, p)
+			}
+		})
+	}
+}
+
+func TestDryRunRefused(t *testing.T) {
+	if _, err := DryRun("testdata"); !errors.Is(err, ErrNotRegularFile) {
+		t.Fatalf("expected ErrNotRegularFile, got %v\n", err)
+	}
+	if _, err := DryRun("testdata/large.bin", WithMaxFileSize(10)); !errors.Is(err, ErrFileTooLarge) {
+		t.Fatalf("expected ErrFileTooLarge, got %v\n", err)
+	}
+}
+
+type TestCanShredTable struct {
+	name string
+	path string
+	opts []Option
+	want error
+}
+
+func TestCanShred(t *testing.T) {
+	exe, err := os.Executable()
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := os.Symlink("../small.bin", "testdata/test/canshred.lnk"); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/canshred.lnk")
+	var tests = []TestCanShredTable{
+		{"Allowed", "testdata/small.bin", nil, nil},
+		{"Symlink", "testdata/test/canshred.lnk", nil, ErrNotRegularFile},
+		{"Directory", "testdata", nil, ErrNotRegularFile},
+		{"TooLarge", "testdata/large.bin", []Option{WithMaxFileSize(10)}, ErrFileTooLarge},
+		{"Self", exe, nil, ErrSelfShred},
+		{"Missing", "testdata/test/nonexistent", nil, fs.ErrNotExist},
+	}
+	before, err := os.ReadFile("testdata/small.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := CanShred(tt.path, tt.opts...)
+			if !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+				t.Fatalf("expected %v, got %v\n", tt.want, err)
+			}
+			s := NewShredder(tt.opts...)
+			s.Close()
+			if serr := s.CanShred(tt.path); (serr == nil) != (err == nil) {
+				t.Fatalf("expected the Shredder to agree, got %v\n", serr)
+			}
+		})
+	}
+	if after, err := os.ReadFile("testdata/small.bin"); err != nil || !bytes.Equal(before, after) {
+		t.Fatalf("expected testdata/small.bin untouched: %v\n", err)
+	}
+}
@@ -0,0 +1,37 @@
+//go:build linux
+
+package tatter
+
+import (
+	"runtime"
+	"syscall"
+)
+
+const (
+	ioprioWhoProcess = 1  // with id 0, the calling thread
+	ioprioClassShift = 13 // the class is in the top bits of the priority
+	ioprioClassIdle  = 3  // only served when no one else needs the disk
+)
+
+// Lowers the I/O priority of the calling goroutine to the idle class,
+// locking it to its thread, since priorities apply to threads. Returns
+// a function that restores the priority and unlocks the thread. If the
+// priority cannot be restored, the thread stays locked so it exits
+// along with the goroutine instead of being reused at idle priority.
+func lowerPriority() func() {
+	runtime.LockOSThread()
+	old, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_GET, ioprioWhoProcess, 0, 0)
+	if errno != 0 {
+		runtime.UnlockOSThread()
+		return func() {}
+	}
+	if _, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, 0, ioprioClassIdle<<ioprioClassShift); errno != 0 {
+		runtime.UnlockOSThread()
+		return func() {}
+	}
+	return func() {
+		if _, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_SET, ioprioWhoProcess, 0, old); errno == 0 {
+			runtime.UnlockOSThread()
+		}
+	}
+}
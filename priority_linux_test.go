@@ -0,0 +1,40 @@
+package tatter
+
+import (
+	"runtime"
+	"syscall"
+	"testing"
+)
+
+func ioprio(t *testing.T) uintptr {
+	p, _, errno := syscall.RawSyscall(syscall.SYS_IOPRIO_GET, ioprioWhoProcess, 0, 0)
+	if errno != 0 {
+		t.Skipf("ioprio_get: %v\n", errno)
+	}
+	return p
+}
+
+func TestLowerPriority(t *testing.T) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+	old := ioprio(t)
+	restore := lowerPriority()
+	if p := ioprio(t); p>>ioprioClassShift != ioprioClassIdle {
+		t.Fatalf("expected the idle class, got priority %#x\n", p)
+	}
+	restore()
+	if p := ioprio(t); p != old {
+		t.Fatalf("expected priority %#x restored, got %#x\n", old, p)
+	}
+}
+
+func TestShredLowPriority(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/lowprio.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	if err := Shred("testdata/test/lowprio.bin", WithLowPriority(true)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+}
@@ -0,0 +1,9 @@
+//go:build !linux
+
+package tatter
+
+// I/O priorities are not supported on this platform, so nothing is
+// lowered.
+func lowerPriority() func() {
+	return func() {}
+}
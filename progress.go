@@ -0,0 +1,85 @@
+package tatter
+
+import (
+	"context"
+	"os"
+	"sync"
+)
+
+// Progress of a shred started with ShredProgress. Written counts the
+// bytes overwritten so far over all passes, out of Total, which is the
+// size of the file times the number of passes.
+type Progress struct {
+	Pass    int
+	Passes  int
+	Written int64
+	Total   int64
+}
+
+// Same as Shred, but runs in the background and reports its progress on
+// the first returned channel as batches are written. Only the latest
+// progress is kept: updates not received in time are replaced by newer
+// ones, so a slow or absent reader never blocks the shred. Once done,
+// the progress channel is closed, and then the result of the shred, nil
+// on success, is sent on the second channel, which is closed right
+// after. Both channels are closed exactly once.
+func ShredProgress(path string, opts ...Option) (<-chan Progress, <-chan error) {
+	progress := make(chan Progress, 1)
+	errc := make(chan error, 1)
+	c := newConfig(opts)
+	p := Progress{Passes: len(c.sources)}
+	if c.xorPass {
+		p.Passes++
+	}
+	if info, err := os.Lstat(path); err == nil {
+		p.Total = info.Size() * int64(p.Passes)
+	}
+	var mu sync.Mutex
+	c.progress = func(pass int, off, n int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		p.Pass = pass
+		p.Written += n
+		select {
+		case <-progress: // replace the update not received yet
+		default:
+		}
+		progress <- p
+	}
+	go func() {
+		_, err := shred(context.Background(), path, c)
+		close(progress)
+		errc <- err
+		close(errc)
+	}()
+	return progress, errc
+}
+
+// Plans the progress of WithDirProgress over the given files, summing
+// their sizes times the number of passes into the grand total, and
+// returns opts extended to report every batch written against it.
+func (c *config) trackDir(opts []Option, files []string) []Option {
+	passes := int64(len(c.sources))
+	if c.xorPass {
+		passes++
+	}
+	var total int64
+	for _, file := range files {
+		if info, err := c.fsys().Lstat(file); err == nil {
+			total += info.Size() * passes
+		}
+	}
+	report := c.dirProgress
+	report(0, total)
+	var mu sync.Mutex
+	var written int64
+	progress := func(pass int, off, n int64) {
+		mu.Lock()
+		defer mu.Unlock()
+		written += n
+		report(written, total)
+	}
+	return append(opts[:len(opts):len(opts)], func(c *config) {
+		c.progress = progress
+	})
+}
@@ -0,0 +1,99 @@
+package tatter
+
+import (
+	"os"
+	"testing"
+	"time"
+)
+
+func TestShredProgress(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/progress.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	progress, errc := ShredProgress("testdata/test/progress.bin", WithWriteCount(100))
+	var last Progress
+	for p := range progress {
+		if p.Written < last.Written || p.Pass < last.Pass {
+			t.Fatalf("progress went back from %+v to %+v\n", last, p)
+		}
+		last = p
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if _, ok := <-errc; ok {
+		t.Fatalf("expected the error channel closed\n")
+	}
+	if last.Passes != 3 || last.Total != 3*40716 || last.Written != last.Total {
+		t.Fatalf("expected 3 passes of 40716 bytes, got %+v\n", last)
+	}
+}
+
+func TestShredProgressXorPass(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/progressxor.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	progress, errc := ShredProgress("testdata/test/progressxor.bin", WithXorPass(true))
+	var last Progress
+	for p := range progress {
+		last = p
+	}
+	if err := <-errc; err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if last.Passes != 4 || last.Total != 4*40716 || last.Written != last.Total {
+		t.Fatalf("expected 4 passes of 40716 bytes, got %+v\n", last)
+	}
+}
+
+func TestShredProgressNotDrained(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/progress2.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	_, errc := ShredProgress("testdata/test/progress2.bin", WithWriteCount(1000))
+	select {
+	case err := <-errc:
+		if err != nil {
+			t.Fatalf("err: %v\n", err)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("shred blocked on a progress channel nobody reads\n")
+	}
+}
+
+func TestShredProgressError(t *testing.T) {
+	progress, errc := ShredProgress("testdata/test/missing.bin")
+	for range progress {
+	}
+	if err := <-errc; err == nil {
+		t.Fatalf("expected a missing file err, got nil\n")
+	}
+}
+
+func TestWithDirProgress(t *testing.T) {
+	root := "testdata/test/dirprogress"
+	createTree(t, root, []string{"a.bin", "sub/b.bin", "sub/deeper/c.bin"})
+	defer os.RemoveAll(root)
+	var calls [][2]int64
+	report := func(written, total int64) {
+		calls = append(calls, [2]int64{written, total})
+	}
+	if _, err := ShredDir(root, WithDirProgress(report)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	const total = 3 * 8 * 3 // files, bytes, passes
+	if len(calls) < 2 || calls[0] != [2]int64{0, total} || calls[len(calls)-1] != [2]int64{total, total} {
+		t.Fatalf("expected progress from 0 to %d, got %v\n", total, calls)
+	}
+	for i := 1; i < len(calls); i++ {
+		if calls[i][0] < calls[i-1][0] || calls[i][1] != total {
+			t.Fatalf("expected steady progress, got %v\n", calls)
+		}
+	}
+}
@@ -0,0 +1,28 @@
+//go:build linux
+
+package tatter
+
+import (
+	"errors"
+	"os"
+	"syscall"
+)
+
+const (
+	fallocKeepSize  = 0x1
+	fallocPunchHole = 0x2
+)
+
+// Deallocates the blocks of the first size bytes of f by punching a
+// hole over them. Filesystems without hole punching support get the
+// file truncated instead.
+func deallocate(f *os.File, size int64) error {
+	if size == 0 {
+		return nil
+	}
+	err := syscall.Fallocate(int(f.Fd()), fallocPunchHole|fallocKeepSize, 0, size)
+	if errors.Is(err, syscall.EOPNOTSUPP) || errors.Is(err, syscall.ENOSYS) {
+		return f.Truncate(0)
+	}
+	return err
+}
@@ -0,0 +1,43 @@
+package tatter
+
+import (
+	"context"
+	"io"
+	"os"
+	"testing"
+)
+
+func TestDeallocate(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/punch.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/punch.bin")
+	defer f.Close()
+	if err := shredFile(context.Background(), f, newConfig(nil), &ShredStats{}); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := deallocate(f, 40716); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	b, err := io.ReadAll(io.NewSectionReader(f, 0, 40716))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	for i, v := range b {
+		if v != 0 {
+			t.Fatalf("byte %d is %#x, want a hole\n", i, v)
+		}
+	}
+}
+
+func TestShredPunchHoles(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/punch2.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	if err := Shred("testdata/test/punch2.bin", WithPunchHoles(true)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+}
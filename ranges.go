@@ -0,0 +1,165 @@
+package tatter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// A part of a file, writing and reading at offsets relative to base.
+type section struct {
+	f    target
+	base int64
+}
+
+func (s section) ReadAt(b []byte, off int64) (int, error) {
+	return s.f.ReadAt(b, s.base+off)
+}
+
+func (s section) WriteAt(b []byte, off int64) (int, error) {
+	return s.f.WriteAt(b, s.base+off)
+}
+
+func (s section) Name() string {
+	return s.f.Name()
+}
+
+// Sorts the ranges and merges the ones that overlap or touch, returning
+// ErrInvalidRange if any of them is reversed or out of [0, size].
+func mergeRanges(ranges [][2]int64, size int64) ([][2]int64, error) {
+	sorted := make([][2]int64, 0, len(ranges))
+	for _, r := range ranges {
+		if r[0] < 0 || r[0] > r[1] || r[1] > size {
+			return nil, fmt.Errorf("%w: [%d, %d) of %d bytes", ErrInvalidRange, r[0], r[1], size)
+		}
+		if r[0] < r[1] {
+			sorted = append(sorted, r)
+		}
+	}
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i][0] < sorted[j][0] })
+	var merged [][2]int64
+	for _, r := range sorted {
+		if n := len(merged); n > 0 && r[0] <= merged[n-1][1] {
+			if r[1] > merged[n-1][1] {
+				merged[n-1][1] = r[1]
+			}
+			continue
+		}
+		merged = append(merged, r)
+	}
+	return merged, nil
+}
+
+// Overwrites the given byte ranges of the file at path with the passes
+// of the options, opening it once, and keeps it: nothing is removed,
+// truncated or renamed. Each range holds the offsets of its first byte
+// and of the byte past its last one, so {0, 512} is the first 512
+// bytes. Ranges that overlap or touch are merged, so no byte is written
+// twice in a pass, and any range out of the file fails with
+// ErrInvalidRange before anything is written. Every pass overwrites all
+// the ranges before the next one starts, and the file is synced as the
+// sync policy says. Patterns are aligned to the start of each range
+// rather than of the file. BytesOverwritten in the stats counts every
+// range of every pass. As with Shred, path is checked with Lstat to be
+// a regular file before it is opened. Options that work on whole files,
+// such as WithXorPass, WithParanoidVerify, WithCheckpoint and
+// WithFileSystem, are refused.
+func ShredRanges(path string, ranges [][2]int64, opts ...Option) (stats ShredStats, err error) {
+	stats.Path = path
+	c := newConfig(opts)
+	if err = c.validate(); err != nil {
+		return stats, err
+	}
+	if name := c.wholeOnly(); name != "" {
+		return stats, fmt.Errorf("%s is not supported by ShredRanges", name)
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return stats, err
+	}
+	if !info.Mode().IsRegular() {
+		return stats, fmt.Errorf("%w: %s is %v", ErrNotRegularFile, path, info.Mode().Type())
+	}
+	c, closeRandom, err := c.openRandom()
+	if err != nil {
+		return stats, err
+	}
+	defer closeRandom()
+	f, err := os.OpenFile(path, os.O_RDWR, 0)
+	if err != nil {
+		return stats, err
+	}
+	defer f.Close()
+	if c.lock {
+		if err = lockFile(f); err != nil {
+			return stats, err
+		}
+	}
+	if info, err = f.Stat(); err != nil {
+		return stats, err
+	}
+	if !info.Mode().IsRegular() { // replaced since the Lstat
+		return stats, fmt.Errorf("%w: %s is %v", ErrNotRegularFile, path, info.Mode().Type())
+	}
+	stats.Size = info.Size()
+	if ranges, err = mergeRanges(ranges, stats.Size); err != nil {
+		return stats, err
+	}
+	start := now()
+	defer func() { stats.Duration = now().Sub(start) }()
+	if err = shredRanges(context.Background(), f, ranges, c, &stats); err != nil {
+		eachShredError(err, func(e *ShredError) { e.Path = path })
+	}
+	return stats, err
+}
+
+// Returns the name of the first option set that only works on whole
+// files, or an empty string if there is none.
+func (c *config) wholeOnly() string {
+	switch {
+	case c.fs != nil:
+		return "WithFileSystem"
+	case c.xorPass:
+		return "WithXorPass"
+	case c.paranoid:
+		return "WithParanoidVerify"
+	case c.checkpoint != "":
+		return "WithCheckpoint"
+	}
+	return ""
+}
+
+// A target that can be synced, such as File.
+type syncTarget interface {
+	target
+	Sync() error
+}
+
+// Overwrites the given ranges of f, merged already, with the passes of
+// the options, every pass over all of them before the next one.
+func shredRanges(ctx context.Context, f syncTarget, ranges [][2]int64, c *config, stats *ShredStats) error {
+	for pass, src := range c.sources {
+		src, err := passSource(src, pass)
+		if err != nil {
+			return &ShredError{Pass: pass, Size: stats.Size, Err: err}
+		}
+		src = c.random(src, pass)
+		for _, r := range ranges {
+			size := r[1] - r[0]
+			_, _, written, err := shredPass(ctx, section{f, r[0]}, size, c.bufSize(size), src, pass, c, false, nil, nil)
+			stats.BytesOverwritten += written
+			if err != nil {
+				eachShredError(err, func(e *ShredError) { e.Offset += r[0] })
+				return err
+			}
+		}
+		if c.syncs(pass, len(c.sources)) {
+			if err := f.Sync(); err != nil {
+				return err
+			}
+		}
+		stats.Passes++
+	}
+	return nil
+}
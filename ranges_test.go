@@ -0,0 +1,108 @@
+package tatter
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type TestMergeRangesTable struct {
+	name   string
+	ranges [][2]int64
+	want   [][2]int64
+	err    error
+}
+
+func TestMergeRanges(t *testing.T) {
+	var tests = []TestMergeRangesTable{
+		{"Empty", nil, nil, nil},
+		{"Disjoint", [][2]int64{{50, 60}, {0, 10}}, [][2]int64{{0, 10}, {50, 60}}, nil},
+		{"Overlapping", [][2]int64{{0, 10}, {5, 20}, {8, 9}}, [][2]int64{{0, 20}}, nil},
+		{"Touching", [][2]int64{{10, 20}, {0, 10}}, [][2]int64{{0, 20}}, nil},
+		{"EmptyRange", [][2]int64{{5, 5}, {90, 100}}, [][2]int64{{90, 100}}, nil},
+		{"Reversed", [][2]int64{{10, 5}}, nil, ErrInvalidRange},
+		{"Negative", [][2]int64{{-1, 5}}, nil, ErrInvalidRange},
+		{"PastEnd", [][2]int64{{90, 101}}, nil, ErrInvalidRange},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := mergeRanges(tt.ranges, 100)
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("got: %v, want %v\n", err, tt.err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("expected %v, got %v\n", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestShredRanges(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/ranges.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/ranges.bin")
+	orig, err := os.ReadFile("testdata/extra.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if _, err := ShredRanges("testdata/test/ranges.bin", [][2]int64{{0, 10}, {40000, 40717}}); !errors.Is(err, ErrInvalidRange) {
+		t.Fatalf("expected ErrInvalidRange, got %v\n", err)
+	}
+	ranges := [][2]int64{{150, 300}, {100, 200}, {1000, 1000}, {40000, 40716}}
+	stats, err := ShredRanges("testdata/test/ranges.bin", ranges, WithStandard(StandardQuick))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if stats.Passes != 1 || stats.BytesOverwritten != 200+716 {
+		t.Fatalf("expected 916 bytes in 1 pass, got %d in %d\n", stats.BytesOverwritten, stats.Passes)
+	}
+	want := append([]byte{}, orig...)
+	copy(want[100:300], make([]byte, 200))
+	copy(want[40000:], make([]byte, 716))
+	got, err := os.ReadFile("testdata/test/ranges.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if !bytes.Equal(got, want) {
+		t.Fatalf("expected only the ranges zeroed\n")
+	}
+}
+
+type TestShredRangesRefusedTable struct {
+	name string
+	path string
+	opts []Option
+	want string
+}
+
+func TestShredRangesRefused(t *testing.T) {
+	var tests = []TestShredRangesRefusedTable{
+		{"Directory", "testdata", nil, ErrNotRegularFile.Error()},
+		{"FileSystem", "testdata/test/refused.bin", []Option{WithFileSystem(newMemFS(nil))}, "WithFileSystem"},
+		{"XorPass", "testdata/test/refused.bin", []Option{WithXorPass(true)}, "WithXorPass"},
+		{"Paranoid", "testdata/test/refused.bin", []Option{WithParanoidVerify(true)}, "WithParanoidVerify"},
+		{"Checkpoint", "testdata/test/refused.bin", []Option{WithCheckpoint("testdata/test/ranges.checkpoint")}, "WithCheckpoint"},
+	}
+	f, err := copyFile(t, "testdata/small.bin", "testdata/test/refused.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/refused.bin")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ShredRanges(tt.path, [][2]int64{{0, 1}}, tt.opts...); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("expected %s refused, got %v\n", tt.want, err)
+			}
+		})
+	}
+	if b, err := os.ReadFile("testdata/test/refused.bin"); err != nil || string(b) != "Small123" {
+		t.Fatalf("expected the file untouched: %v\n", err)
+	}
+}
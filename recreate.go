@@ -0,0 +1,36 @@
+package tatter
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// Size up to which files may be stored inline, within their inode, as
+// ext4 with inline_data does for files that fit in the inode, or btrfs
+// for files up to max_inline, 2048 bytes by default.
+const inlineSize int64 = 4096
+
+// Replaces the file at path with a new one holding size zeros, created
+// next to it and renamed over it, so its name leads to a new inode.
+// Both the new file and the directory are synced.
+func recreate(path string, size int64) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tatter-*")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(make([]byte, size))
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
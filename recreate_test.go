@@ -0,0 +1,112 @@
+package tatter
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRecreate(t *testing.T) {
+	f, err := copyFile(t, "testdata/small.bin", "testdata/test/recreate.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/recreate.bin")
+	before, err := os.Stat("testdata/test/recreate.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := recreate("testdata/test/recreate.bin", before.Size()); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	after, err := os.Stat("testdata/test/recreate.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if os.SameFile(before, after) {
+		t.Fatalf("expected a new file\n")
+	}
+	b, err := os.ReadFile("testdata/test/recreate.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if !bytes.Equal(b, make([]byte, before.Size())) {
+		t.Fatalf("expected %d zeros, got %x\n", before.Size(), b)
+	}
+	tmps, _ := filepath.Glob("testdata/test/.tatter-*")
+	if len(tmps) != 0 {
+		t.Fatalf("expected no temporary file left, got %v\n", tmps)
+	}
+}
+
+type TestWithRecreateSmallTable struct {
+	name     string
+	src      string
+	enabled  bool
+	recreate bool
+}
+
+func TestWithRecreateSmall(t *testing.T) {
+	var tests = []TestWithRecreateSmallTable{
+		{"Small", "testdata/small.bin", true, true},
+		{"Large", "testdata/extra.bin", true, false},
+		{"Disabled", "testdata/small.bin", false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := copyFile(t, tt.src, "testdata/test/recreate.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			f.Close()
+			defer os.Remove("testdata/test/recreate.bin")
+			var mu sync.Mutex
+			recreated := false
+			logger := LoggerFunc(func(level LogLevel, msg string) {
+				mu.Lock()
+				defer mu.Unlock()
+				recreated = recreated || strings.HasPrefix(msg, "recreated ")
+			})
+			opts := []Option{WithRecreateSmall(tt.enabled), WithLogger(logger), WithLogLevel(LevelDebug)}
+			if err := Shred("testdata/test/recreate.bin", opts...); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			if _, err := os.Stat("testdata/test/recreate.bin"); err == nil {
+				t.Fatalf("expected the file removed\n")
+			}
+			if recreated != tt.recreate {
+				t.Fatalf("expected recreated %v, got %v\n", tt.recreate, recreated)
+			}
+		})
+	}
+}
+
+func TestWithRecreateSmallStats(t *testing.T) {
+	f, err := copyFile(t, "testdata/small.bin", "testdata/test/recreate.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/recreate.bin")
+	var removed os.FileInfo
+	remove = func(name string) error {
+		removed, _ = os.Lstat(name)
+		return os.Remove(name)
+	}
+	defer func() { remove = os.Remove }()
+	opts := []Option{WithRecreateSmall(true), WithLock(true), WithDurableRemove(true)}
+	stats, err := ShredWithStats("testdata/test/recreate.bin", opts...)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if removed == nil {
+		t.Fatalf("expected the file removed\n")
+	}
+	if want := fileID("", removed).ino; stats.Inode != want {
+		t.Fatalf("expected the inode of the recreated file, %d, got %d\n", want, stats.Inode)
+	}
+}
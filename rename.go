@@ -0,0 +1,111 @@
+package tatter
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math/big"
+	"os"
+	"path/filepath"
+)
+
+const nameChars = "abcdefghijklmnopqrstuvwxyz0123456789"
+const nameAttempts = 10
+
+// Returns a random name of n chars from nameChars.
+func randomName(n int) (string, error) {
+	b := make([]byte, n)
+	chars := big.NewInt(int64(len(nameChars)))
+	for i := range b {
+		r, err := rand.Int(rand.Reader, chars)
+		if err != nil {
+			return "", err
+		}
+		b[i] = nameChars[r.Int64()]
+	}
+	return string(b), nil
+}
+
+// Length of the i-th of n successive names for a file whose name had l
+// chars: the first keeps the original length, and the following ones
+// shrink evenly down to a single char for the last one.
+func nameLen(l, i, n int) int {
+	if n == 1 || l <= 1 {
+		return l
+	}
+	return l - i*(l-1)/(n-1)
+}
+
+// Renames the file at path n times within its directory, each time to a
+// new random name, syncing the directory after every rename so each
+// name reaches the directory entry on disk. Returns the final path of
+// the file.
+func obfuscateName(path string, n int) (string, error) {
+	dir, base := filepath.Split(path)
+	l := len(base)
+	for i := 0; i < n; i++ {
+		next, err := linkFreeName(path, dir, nameLen(l, i, n))
+		if err != nil {
+			return path, err
+		}
+		path = next
+		if err := syncDir(dir); err != nil {
+			return path, err
+		}
+	}
+	return path, nil
+}
+
+// Renames the file at path to name within its directory, or to name
+// followed by -1, -2 and so on if that exists already, syncing the
+// directory. The new name is linked first and the old one removed
+// after, since a rename would replace a file created under the new name
+// in the meantime. Returns the final path of the file.
+func renameFinal(path, name string) (string, error) {
+	path = filepath.Clean(path)
+	dir := filepath.Dir(path)
+	next := filepath.Join(dir, name)
+	for i := 1; ; i++ {
+		if next == path {
+			return path, nil // named so already
+		}
+		err := os.Link(path, next)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, fs.ErrExist) {
+			return path, err
+		}
+		next = filepath.Join(dir, fmt.Sprintf("%s-%d", name, i))
+	}
+	if err := os.Remove(path); err != nil {
+		return next, err
+	}
+	return next, syncDir(dir)
+}
+
+// Moves the file at path to a random name of l chars in dir, linking
+// the new name first and removing the old one after, since a rename
+// would replace a file created under the new name in the meantime.
+// Names that already exist are regenerated, getting longer if a length
+// keeps colliding, so no other file is ever replaced. Returns the new
+// path of the file.
+func linkFreeName(path, dir string, l int) (string, error) {
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 && attempt%nameAttempts == 0 {
+			l++
+		}
+		name, err := randomName(l)
+		if err != nil {
+			return path, err
+		}
+		next := filepath.Join(dir, name)
+		if err := os.Link(path, next); errors.Is(err, fs.ErrExist) {
+			continue
+		} else if err != nil {
+			return path, err
+		}
+		return next, os.Remove(path)
+	}
+}
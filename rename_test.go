@@ -0,0 +1,196 @@
+package tatter
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type TestNameLenTable struct {
+	name    string
+	l, i, n int
+	want    int
+}
+
+func TestNameLen(t *testing.T) {
+	var tests = []TestNameLenTable{
+		{"Single", 12, 0, 1, 12},
+		{"First", 12, 0, 4, 12},
+		{"Middle", 12, 2, 4, 5},
+		{"Last", 12, 3, 4, 1},
+		{"ShortName", 1, 2, 3, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nameLen(tt.l, tt.i, tt.n); got != tt.want {
+				t.Fatalf("expected %d, got %d\n", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestObfuscateName(t *testing.T) {
+	dir := "testdata/test/rename"
+	if err := os.Mkdir(dir, 0755); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.RemoveAll(dir)
+	createTree(t, dir, []string{"secret-name.bin"})
+	path, err := obfuscateName(filepath.Join(dir, "secret-name.bin"), 3)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if len(filepath.Base(path)) != 1 {
+		t.Fatalf("final name %s is not short\n", path)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || entries[0].Name() != filepath.Base(path) {
+		t.Fatalf("unexpected directory content %v: %v\n", entries, err)
+	}
+}
+
+func TestLinkFreeNameCollisions(t *testing.T) {
+	dir := "testdata/test/collide"
+	var files []string
+	for _, c := range nameChars {
+		files = append(files, string(c))
+	}
+	createTree(t, dir, append(files, "secret.bin"))
+	defer os.RemoveAll(dir)
+	path, err := linkFreeName(filepath.Join(dir, "secret.bin"), dir, 1)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if len(filepath.Base(path)) != 2 {
+		t.Fatalf("expected a longer name, got %s\n", path)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != len(files)+1 {
+		t.Fatalf("expected every other file kept, got %v: %v\n", entries, err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "secret.bin")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the old name gone, got %v\n", err)
+	}
+}
+
+func TestShredRenameCount(t *testing.T) {
+	dir := "testdata/test/renamed"
+	createTree(t, dir, []string{"a-long-secret-name.bin", "other.bin"})
+	defer os.RemoveAll(dir)
+	if err := Shred(filepath.Join(dir, "a-long-secret-name.bin"), WithRenameCount(5)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := Shred(filepath.Join(dir, "other.bin"), WithRename(true)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("unexpected directory content %v: %v\n", entries, err)
+	}
+}
+
+func TestWithoutOverwrite(t *testing.T) {
+	dir := "testdata/test/nooverwrite"
+	createTree(t, dir, []string{"secret.bin"})
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "secret.bin")
+	// The second name shows the content is left as it is.
+	if err := os.Link(path, "testdata/test/nooverwrite.bin"); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/nooverwrite.bin")
+	stats, err := ShredWithStats(path, WithoutOverwrite(true))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if stats.Passes != 0 || stats.BytesOverwritten != 0 {
+		t.Fatalf("expected nothing overwritten, got %d bytes in %d passes\n", stats.BytesOverwritten, stats.Passes)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 0 {
+		t.Fatalf("unexpected directory content %v: %v\n", entries, err)
+	}
+	got, err := os.ReadFile("testdata/test/nooverwrite.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	want, err := os.ReadFile("testdata/small.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if string(got) != string(want) {
+		t.Fatalf("expected the content untouched, got %x\n", got)
+	}
+	if err := Scrub("testdata/test/nooverwrite.bin", WithoutOverwrite(true)); err == nil {
+		t.Fatalf("expected Scrub to refuse WithoutOverwrite\n")
+	}
+}
+
+type TestWithFinalNameTable struct {
+	name     string
+	file     string
+	existing []string
+	want     string
+}
+
+func TestWithFinalName(t *testing.T) {
+	var tests = []TestWithFinalNameTable{
+		{"Free", "secret.bin", nil, "DELETED_BY_POLICY"},
+		{"Collision", "secret.bin", []string{"DELETED_BY_POLICY", "DELETED_BY_POLICY-1"}, "DELETED_BY_POLICY-2"},
+		{"Named", "DELETED_BY_POLICY", nil, "DELETED_BY_POLICY"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := "testdata/test/finalname"
+			createTree(t, dir, append([]string{tt.file}, tt.existing...))
+			defer os.RemoveAll(dir)
+			var removed string
+			remove = func(name string) error {
+				removed = name
+				return os.Remove(name)
+			}
+			defer func() { remove = os.Remove }()
+			if err := Shred(filepath.Join(dir, tt.file), WithFinalName("DELETED_BY_POLICY")); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			if removed != filepath.Join(dir, tt.want) {
+				t.Fatalf("expected %s removed, got %s\n", tt.want, removed)
+			}
+			entries, err := os.ReadDir(dir)
+			if err != nil || len(entries) != len(tt.existing) {
+				t.Fatalf("expected %v left, got %v: %v\n", tt.existing, entries, err)
+			}
+		})
+	}
+	for _, name := range []string{"", ".", "..", "dir/name"} {
+		if err := Shred("testdata/test/nonexistent", WithFinalName(name)); err == nil || errors.Is(err, os.ErrNotExist) {
+			t.Fatalf("expected final name %q refused, got %v\n", name, err)
+		}
+	}
+	if err := Shred("testdata/test/nonexistent", WithFinalName("DELETED"), WithRename(true)); err == nil || errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected WithRename refused, got %v\n", err)
+	}
+}
+
+func TestRenameFinal(t *testing.T) {
+	dir := "testdata/test/renamefinal"
+	createTree(t, dir, []string{"secret.bin"})
+	defer os.RemoveAll(dir)
+	if err := os.WriteFile(filepath.Join(dir, "DELETED"), []byte("other"), 0o600); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	path, err := renameFinal(filepath.Join(dir, "secret.bin"), "DELETED")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if path != filepath.Join(dir, "DELETED-1") {
+		t.Fatalf("expected DELETED-1, got %s\n", path)
+	}
+	if b, err := os.ReadFile(filepath.Join(dir, "DELETED")); err != nil || string(b) != "other" {
+		t.Fatalf("expected the other file untouched, got %q: %v\n", b, err)
+	}
+	if _, err := os.Lstat(filepath.Join(dir, "secret.bin")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the old name gone, got %v\n", err)
+	}
+}
@@ -0,0 +1,95 @@
+package tatter
+
+import "fmt"
+
+// How likely it is that the original content of a shreded file can
+// still be recovered.
+type RiskLevel int
+
+const (
+	RiskLow    RiskLevel = iota // the content was overwritten in place
+	RiskMedium                  // the content may survive elsewhere
+	RiskHigh                    // the content likely survives
+)
+
+func (r RiskLevel) String() string {
+	switch r {
+	case RiskLow:
+		return "low"
+	case RiskMedium:
+		return "medium"
+	case RiskHigh:
+		return "high"
+	}
+	return fmt.Sprintf("RiskLevel(%d)", int(r))
+}
+
+// Filesystems that write modified blocks somewhere else instead of in
+// place, so overwriting leaves the original blocks untouched.
+var outOfPlace = map[string]bool{
+	"apfs":     true,
+	"bcachefs": true,
+	"btrfs":    true,
+	"f2fs":     true,
+	"refs":     true,
+	"zfs":      true,
+}
+
+// Filesystems where the data is stored out of reach of this host, which
+// may cache, snapshot or replicate it.
+var remoteFS = map[string]bool{
+	"cifs": true,
+	"fuse": true,
+	"nfs":  true,
+	"smb2": true,
+}
+
+// Filesystems held in memory only, where syncing is a no-op since there
+// is no storage for the data to reach.
+var volatileFS = map[string]bool{
+	"ramfs": true,
+	"tmpfs": true,
+}
+
+// Checks before overwriting the file at path that its filesystem can
+// make the removal of WithDurableRemove durable. Volatile filesystems
+// and remote ones, whose server or daemon decides what a sync does, are
+// flagged: a warning is logged, or with WithStrictDurability,
+// ErrDurabilityUnsupported is returned. Unknown filesystems are not.
+func (c *config) checkDurability(path, fsType string) error {
+	var reason string
+	switch {
+	case volatileFS[fsType]:
+		reason = "held in memory, so nothing reaches the storage"
+	case remoteFS[fsType]:
+		reason = "remote, so syncs may not reach the storage of the server"
+	default:
+		return nil
+	}
+	if c.strictDurable {
+		return fmt.Errorf("%w: %s is on %s, %s", ErrDurabilityUnsupported, path, fsType, reason)
+	}
+	c.logf(LevelWarn, "%s is on %s, %s: its removal may not be durable", path, fsType, reason)
+	return nil
+}
+
+// Classifies the residual risk left after the shred described by stats,
+// returning the level along with a human readable reason. Only the
+// worst finding is reported. It is a heuristic over the collected
+// stats: storage below the filesystem (SSD wear leveling, RAID, backups)
+// is not taken into account, and can keep copies even at RiskLow.
+func ResidualRisk(stats ShredStats) (RiskLevel, string) {
+	switch {
+	case stats.Passes == 0 || stats.BytesOverwritten < stats.Size:
+		return RiskHigh, "the content was not completely overwritten"
+	case outOfPlace[stats.Filesystem]:
+		return RiskHigh, fmt.Sprintf("%s is copy-on-write, so passes were written to new blocks and the original ones may remain until reused", stats.Filesystem)
+	case stats.HardLinkCount > 1:
+		return RiskMedium, fmt.Sprintf("the file has %d hard links, so it is still reachable and its blocks were not freed", stats.HardLinkCount)
+	case remoteFS[stats.Filesystem]:
+		return RiskMedium, fmt.Sprintf("%s is a remote filesystem, which may keep snapshots or cached copies", stats.Filesystem)
+	case stats.Filesystem == "":
+		return RiskMedium, "the filesystem is unknown, so overwriting in place cannot be assumed"
+	}
+	return RiskLow, fmt.Sprintf("the content was overwritten in place on %s with %d passes", stats.Filesystem, stats.Passes)
+}
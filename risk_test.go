@@ -0,0 +1,84 @@
+package tatter
+
+import (
+	"errors"
+	"os"
+	"runtime"
+	"testing"
+)
+
+type TestResidualRiskTable struct {
+	name  string
+	stats ShredStats
+	want  RiskLevel
+}
+
+func TestResidualRisk(t *testing.T) {
+	var tests = []TestResidualRiskTable{
+		{"InPlace", ShredStats{Size: 10, BytesOverwritten: 30, Passes: 3, HardLinkCount: 1, Filesystem: "ext4"}, RiskLow},
+		{"Empty", ShredStats{Passes: 1, HardLinkCount: 1, Filesystem: "xfs"}, RiskLow},
+		{"Partial", ShredStats{Size: 10, BytesOverwritten: 5, HardLinkCount: 1, Filesystem: "ext4"}, RiskHigh},
+		{"NoPasses", ShredStats{Filesystem: "ext4"}, RiskHigh},
+		{"CopyOnWrite", ShredStats{Size: 10, BytesOverwritten: 10, Passes: 1, HardLinkCount: 1, Filesystem: "btrfs"}, RiskHigh},
+		{"HardLinks", ShredStats{Size: 10, BytesOverwritten: 10, Passes: 1, HardLinkCount: 2, Filesystem: "ext4"}, RiskMedium},
+		{"Remote", ShredStats{Size: 10, BytesOverwritten: 10, Passes: 1, HardLinkCount: 1, Filesystem: "nfs"}, RiskMedium},
+		{"Unknown", ShredStats{Size: 10, BytesOverwritten: 10, Passes: 1, HardLinkCount: 1}, RiskMedium},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, reason := ResidualRisk(tt.stats)
+			if got != tt.want {
+				t.Fatalf("expected %v, got %v: %s\n", tt.want, got, reason)
+			}
+			if reason == "" {
+				t.Fatalf("expected a reason, got none\n")
+			}
+		})
+	}
+}
+
+func TestFilesystemType(t *testing.T) {
+	f, err := os.Open("testdata/small.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer f.Close()
+	got := filesystemType(f)
+	if runtime.GOOS == "darwin" && got == "" {
+		t.Fatalf("expected a filesystem name, got none\n")
+	}
+	t.Logf("testdata is on %q\n", got)
+}
+
+type TestCheckDurabilityTable struct {
+	name   string
+	fsType string
+	strict bool
+	err    bool
+	warn   bool
+}
+
+func TestCheckDurability(t *testing.T) {
+	var tests = []TestCheckDurabilityTable{
+		{"Disk", "ext4", true, false, false},
+		{"Unknown", "", true, false, false},
+		{"Tmpfs", "tmpfs", false, false, true},
+		{"TmpfsStrict", "tmpfs", true, true, false},
+		{"Ramfs", "ramfs", true, true, false},
+		{"NFS", "nfs", false, false, true},
+		{"FuseStrict", "fuse", true, true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &testLogger{}
+			c := newConfig([]Option{WithDurableRemove(true), WithStrictDurability(tt.strict), WithLogger(l)})
+			err := c.checkDurability("secret.key", tt.fsType)
+			if errors.Is(err, ErrDurabilityUnsupported) != tt.err {
+				t.Fatalf("expected ErrDurabilityUnsupported: %v, got %v\n", tt.err, err)
+			}
+			if warned := len(l.msgs[LevelWarn]) > 0; warned != tt.warn {
+				t.Fatalf("expected a warning: %v, got %v\n", tt.warn, l.msgs[LevelWarn])
+			}
+		})
+	}
+}
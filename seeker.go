@@ -0,0 +1,82 @@
+package tatter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sync"
+)
+
+// Reads and writes at offsets of a ReadWriteSeeker, seeking before every
+// call, so calls are serialized.
+type seekerAt struct {
+	mu  sync.Mutex
+	rws io.ReadWriteSeeker
+}
+
+func (s *seekerAt) ReadAt(b []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.rws.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	n, err := io.ReadFull(s.rws, b)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (s *seekerAt) WriteAt(b []byte, off int64) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, err := s.rws.Seek(off, io.SeekStart); err != nil {
+		return 0, err
+	}
+	return s.rws.Write(b)
+}
+
+// Returns the name of rws if it has one, as *os.File does.
+func (s *seekerAt) Name() string {
+	if n, ok := s.rws.(interface{ Name() string }); ok {
+		return n.Name()
+	}
+	return "seeker"
+}
+
+// Syncs rws if it can be synced, as *os.File can.
+func (s *seekerAt) Sync() error {
+	if f, ok := s.rws.(interface{ Sync() error }); ok {
+		return f.Sync()
+	}
+	return nil
+}
+
+// Overwrites the content behind rws with the passes of the options, for
+// backends that can seek but not write at an offset. Its size is the
+// offset of its end, as Seek tells, and every pass then seeks to each
+// batch and writes it from a single thread, since rws has a single
+// position. It is synced as the sync policy says if it has a Sync
+// method. Nothing is removed, and the position is left wherever the
+// last write ended.
+func ShredSeeker(rws io.ReadWriteSeeker, opts ...Option) error {
+	c := newConfig(opts)
+	if err := c.validate(); err != nil {
+		return err
+	}
+	c.sequential = true
+	c, closeRandom, err := c.openRandom()
+	if err != nil {
+		return err
+	}
+	defer closeRandom()
+	size, err := rws.Seek(0, io.SeekEnd)
+	if err != nil {
+		return err
+	}
+	var ranges [][2]int64
+	if size > 0 {
+		ranges = [][2]int64{{0, size}}
+	}
+	return shredRanges(context.Background(), &seekerAt{rws: rws}, ranges, c, &ShredStats{Size: size})
+}
@@ -0,0 +1,87 @@
+package tatter
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"sync/atomic"
+	"testing"
+)
+
+// In-memory ReadWriteSeeker, failing writes that overlap in time.
+type memSeeker struct {
+	b       []byte
+	pos     int64
+	writing atomic.Bool
+}
+
+func (m *memSeeker) Read(b []byte) (int, error) {
+	if m.pos >= int64(len(m.b)) {
+		return 0, io.EOF
+	}
+	n := copy(b, m.b[m.pos:])
+	m.pos += int64(n)
+	return n, nil
+}
+
+func (m *memSeeker) Write(b []byte) (int, error) {
+	if !m.writing.CompareAndSwap(false, true) {
+		return 0, errors.New("concurrent write")
+	}
+	defer m.writing.Store(false)
+	if end := m.pos + int64(len(b)); end > int64(len(m.b)) {
+		m.b = append(m.b, make([]byte, end-int64(len(m.b)))...)
+	}
+	n := copy(m.b[m.pos:], b)
+	m.pos += int64(n)
+	return n, nil
+}
+
+func (m *memSeeker) Seek(off int64, whence int) (int64, error) {
+	switch whence {
+	case io.SeekCurrent:
+		off += m.pos
+	case io.SeekEnd:
+		off += int64(len(m.b))
+	}
+	if off < 0 {
+		return 0, errors.New("negative position")
+	}
+	m.pos = off
+	return off, nil
+}
+
+type TestShredSeekerTable struct {
+	name string
+	size int
+	opts []Option
+	want []byte
+}
+
+func TestShredSeeker(t *testing.T) {
+	var tests = []TestShredSeekerTable{
+		{"Empty", 0, nil, nil},
+		{"Constant", 100 << 10, []Option{WithPassSources(ConstantSource(0xff))}, bytes.Repeat([]byte{0xff}, 100<<10)},
+		{"Pattern", 10001, []Option{WithPassSources(ConstantSource(0), PatternSource{1, 2, 3})}, bytes.Repeat([]byte{1, 2, 3}, 10001/3+1)[:10001]},
+		{"ReadAfterWrite", 5000, []Option{WithPassSources(ConstantSource(0x55)), WithReadAfterWrite(true)}, bytes.Repeat([]byte{0x55}, 5000)},
+		{"Default", 50000, nil, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			orig := bytes.Repeat([]byte("secret"), tt.size/6+1)[:tt.size]
+			m := &memSeeker{b: append([]byte(nil), orig...)}
+			if err := ShredSeeker(m, tt.opts...); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			if len(m.b) != tt.size {
+				t.Fatalf("expected %d bytes, got %d\n", tt.size, len(m.b))
+			}
+			if tt.want != nil && !bytes.Equal(m.b, tt.want) {
+				t.Fatalf("unexpected content\n")
+			}
+			if tt.want == nil && tt.size > 0 && bytes.Equal(m.b, orig) {
+				t.Fatalf("expected the content overwritten\n")
+			}
+		})
+	}
+}
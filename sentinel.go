@@ -0,0 +1,25 @@
+package tatter
+
+import (
+	"io"
+	"time"
+)
+
+// Start of the marker written by WithSentinelMarker.
+const sentinelMagic = "TATTER-SHREDDED\x00"
+
+// Returns the marker identifying a file as shreded at time t.
+func sentinel(t time.Time) []byte {
+	return []byte(sentinelMagic + t.UTC().Format(time.RFC3339))
+}
+
+// Writes the marker for time t at the start of f, cut to size bytes so
+// the file does not grow.
+func writeSentinel(f io.WriterAt, size int64, t time.Time) error {
+	b := sentinel(t)
+	if int64(len(b)) > size {
+		b = b[:size]
+	}
+	_, err := f.WriteAt(b, 0)
+	return err
+}
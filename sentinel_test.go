@@ -0,0 +1,56 @@
+package tatter
+
+import (
+	"bytes"
+	"os"
+	"testing"
+	"time"
+)
+
+type TestSentinelTable struct {
+	name string
+	file string
+	want string
+}
+
+func TestWithSentinelMarker(t *testing.T) {
+	var tests = []TestSentinelTable{
+		{"Large", "testdata/large.bin", "TATTER-SHREDDED\x00"},
+		{"Small", "testdata/small.bin", "TATTER-S"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := os.Stat(tt.file)
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			f, err := copyFile(t, tt.file, "testdata/test/sentinel.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			f.Close()
+			defer os.Remove("testdata/test/sentinel.bin")
+			before := time.Now().UTC().Truncate(time.Second)
+			if err := Scrub("testdata/test/sentinel.bin", WithSentinelMarker(true)); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			b, err := os.ReadFile("testdata/test/sentinel.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			if int64(len(b)) != info.Size() {
+				t.Fatalf("expected size %d, got %d\n", info.Size(), len(b))
+			}
+			if !bytes.HasPrefix(b, []byte(tt.want)) {
+				t.Fatalf("expected marker %q, got %q\n", tt.want, b[:len(tt.want)])
+			}
+			if len(b) < 36 {
+				return
+			}
+			stamp, err := time.Parse(time.RFC3339, string(b[16:36]))
+			if err != nil || stamp.Before(before) {
+				t.Fatalf("expected a timestamp after %v, got %q: %v\n", before, b[16:36], err)
+			}
+		})
+	}
+}
@@ -0,0 +1,163 @@
+package tatter
+
+import (
+	"context"
+	"os"
+	"sync"
+	"sync/atomic"
+)
+
+// Shreds files with a set of options given once, for programs that
+// shred many files the same way, such as daemons. Its methods are safe
+// for concurrent use, and the options can be replaced at any time with
+// Reset. Once shut down with Shutdown or Close, it refuses new work
+// with ErrShredderClosed. The zero value shreds with the default
+// options.
+type Shredder struct {
+	opts     atomic.Pointer[[]Option]
+	mu       sync.Mutex
+	closed   bool
+	inFlight sync.WaitGroup
+	ctx      context.Context // cancels the calls in flight, created lazily
+	cancel   context.CancelFunc
+}
+
+// Returns a Shredder using the given options.
+func NewShredder(opts ...Option) *Shredder {
+	s := &Shredder{}
+	s.Reset(opts...)
+	return s
+}
+
+// Replaces the options of s atomically, even while other goroutines are
+// calling its methods. Every call takes the options once, when it
+// starts, and uses them until it returns: calls in flight when Reset
+// returns keep the old options, including every file of a ShredAll or
+// ShredDir in progress, while calls started afterwards use the new ones.
+// The old and new options are never mixed within a call. With
+// WithAutoTuneThreads, the thread count is measured before the options
+// are replaced, unless it was already.
+func (s *Shredder) Reset(opts ...Option) {
+	opts = append([]Option(nil), opts...)
+	if c := newConfig(opts); c.autoTune {
+		dir := os.TempDir()
+		n, err := autoThreads(dir)
+		if err != nil {
+			c.logf(LevelWarn, "could not tune threads in %s, using %d: %v", dir, threads, err)
+		} else {
+			c.logf(LevelInfo, "tuned to %d threads for %s", n, dir)
+			opts = append(opts, func(c *config) { c.tunedThreads = n })
+		}
+	}
+	s.opts.Store(&opts)
+}
+
+// Returns the current options of s.
+func (s *Shredder) options() []Option {
+	if p := s.opts.Load(); p != nil {
+		return *p
+	}
+	return nil
+}
+
+// Registers a call in flight, returning the context that cancels it on
+// shutdown, or ErrShredderClosed.
+func (s *Shredder) begin() (context.Context, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil, ErrShredderClosed
+	}
+	if s.ctx == nil {
+		s.ctx, s.cancel = context.WithCancel(context.Background())
+	}
+	s.inFlight.Add(1)
+	return s.ctx, nil
+}
+
+// Same as the Shred function, with the options of s.
+func (s *Shredder) Shred(path string) error {
+	_, err := s.ShredWithStats(path)
+	return err
+}
+
+// Same as the ShredWithStats function, with the options of s.
+func (s *Shredder) ShredWithStats(path string) (ShredStats, error) {
+	ctx, err := s.begin()
+	if err != nil {
+		return ShredStats{Path: path}, err
+	}
+	defer s.inFlight.Done()
+	return ShredContext(ctx, path, s.options()...)
+}
+
+// Same as the CanShred function, with the options of s. It does not
+// count as a call in flight, and still answers once s is shut down.
+func (s *Shredder) CanShred(path string) error {
+	return CanShred(path, s.options()...)
+}
+
+// Same as the ShredAll function, with the options of s.
+func (s *Shredder) ShredAll(paths []string) (map[string]Result, error) {
+	ctx, err := s.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer s.inFlight.Done()
+	specs := make([]PathSpec, len(paths))
+	for i, path := range paths {
+		specs[i].Path = path
+	}
+	return shredAll(ctx, specs, s.options())
+}
+
+// Same as the ShredDir function, with the options of s.
+func (s *Shredder) ShredDir(root string) (map[string]Result, error) {
+	ctx, err := s.begin()
+	if err != nil {
+		return nil, err
+	}
+	defer s.inFlight.Done()
+	return ShredDirContext(ctx, root, s.options()...)
+}
+
+// Shuts s down: new calls are refused with ErrShredderClosed right away,
+// and the calls in flight are waited for. If ctx is done first, they
+// are cancelled, stopping between batches as ShredContext does, leaving
+// their files partially overwritten and not removed, and ctx.Err() is
+// returned once they have returned. s holds no resources besides its
+// calls, so nothing else is released. Only the first call shuts s down,
+// the next ones return ErrShredderClosed.
+func (s *Shredder) Shutdown(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return ErrShredderClosed
+	}
+	s.closed = true
+	cancel := s.cancel
+	s.mu.Unlock()
+	done := make(chan struct{})
+	go func() {
+		s.inFlight.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		if cancel != nil {
+			cancel()
+		}
+		return nil
+	case <-ctx.Done():
+		if cancel != nil {
+			cancel()
+		}
+		<-done
+		return ctx.Err()
+	}
+}
+
+// Same as Shutdown, waiting for the calls in flight to complete.
+func (s *Shredder) Close() error {
+	return s.Shutdown(context.Background())
+}
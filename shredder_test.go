@@ -0,0 +1,214 @@
+package tatter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"runtime"
+	"sync"
+	"testing"
+)
+
+type TestShredderTable struct {
+	name string
+	s    *Shredder
+	want int
+}
+
+func TestShredder(t *testing.T) {
+	reset := NewShredder(WithStandard(StandardQuick))
+	reset.Reset(WithPasses(2))
+	var tests = []TestShredderTable{
+		{"Zero", &Shredder{}, 3},
+		{"New", NewShredder(WithStandard(StandardQuick)), 1},
+		{"Reset", reset, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := copyFile(t, "testdata/large.bin", "testdata/test/shredder.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			f.Close()
+			defer os.Remove("testdata/test/shredder.bin")
+			stats, err := tt.s.ShredWithStats("testdata/test/shredder.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			if stats.Passes != tt.want {
+				t.Fatalf("expected %d passes, got %d\n", tt.want, stats.Passes)
+			}
+		})
+	}
+}
+
+func TestShredderResetConcurrent(t *testing.T) {
+	s := NewShredder(WithPasses(1))
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		path := fmt.Sprintf("testdata/test/shredder%d.bin", i)
+		f, err := copyFile(t, "testdata/large.bin", path)
+		if err != nil {
+			t.Fatalf("err: %v\n", err)
+		}
+		f.Close()
+		defer os.Remove(path)
+		wg.Add(2)
+		go func(i int) {
+			defer wg.Done()
+			s.Reset(WithPasses(i%2 + 1))
+		}(i)
+		go func() {
+			defer wg.Done()
+			stats, err := s.ShredWithStats(path)
+			if err != nil || stats.Passes < 1 || stats.Passes > 2 {
+				t.Errorf("expected 1 or 2 passes, got %d, err: %v\n", stats.Passes, err)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// Returns a Shredder whose shreds block once confirmed until release is
+// closed, signaling started first.
+func blockedShredder(started chan<- struct{}, release <-chan struct{}) *Shredder {
+	return NewShredder(WithConfirm(func(path string) bool {
+		started <- struct{}{}
+		<-release
+		return true
+	}))
+}
+
+func TestShredderClose(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/close.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/close.bin")
+	started, release := make(chan struct{}), make(chan struct{})
+	s := blockedShredder(started, release)
+	errs := make(chan error)
+	go func() { errs <- s.Shred("testdata/test/close.bin") }()
+	<-started
+	closed := make(chan error)
+	go func() { closed <- s.Close() }()
+	for {
+		s.mu.Lock()
+		done := s.closed
+		s.mu.Unlock()
+		if done {
+			break
+		}
+		runtime.Gosched()
+	}
+	if err := s.Shred("testdata/test/close.bin"); !errors.Is(err, ErrShredderClosed) {
+		t.Fatalf("expected ErrShredderClosed, got %v\n", err)
+	}
+	close(release)
+	if err := <-errs; err != nil {
+		t.Fatalf("expected the shred in flight drained, got %v\n", err)
+	}
+	if err := <-closed; err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := s.Close(); !errors.Is(err, ErrShredderClosed) {
+		t.Fatalf("expected ErrShredderClosed, got %v\n", err)
+	}
+}
+
+func TestShredderShutdownCancel(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/shutdown.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/shutdown.bin")
+	started, release := make(chan struct{}), make(chan struct{})
+	s := blockedShredder(started, release)
+	errs := make(chan error)
+	go func() { errs <- s.Shred("testdata/test/shutdown.bin") }()
+	<-started
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	shut := make(chan error)
+	go func() { shut <- s.Shutdown(ctx) }()
+	for s.ctx.Err() == nil {
+		runtime.Gosched()
+	}
+	close(release)
+	if err := <-errs; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the shred in flight cancelled, got %v\n", err)
+	}
+	if err := <-shut; !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v\n", err)
+	}
+	if _, err := os.Stat("testdata/test/shutdown.bin"); err != nil {
+		t.Fatalf("expected the cancelled file kept, got %v\n", err)
+	}
+}
+
+type TestAutoTuneThreadsTable struct {
+	name string
+	opts []Option
+	want []int
+}
+
+func TestWithAutoTuneThreads(t *testing.T) {
+	var tests = []TestAutoTuneThreadsTable{
+		{"Default", nil, []int{threads}},
+		{"Tuned", []Option{WithAutoTuneThreads(true)}, tuneThreads},
+		{"Shards", []Option{WithAutoTuneThreads(true), WithShardCount(8)}, []int{8}},
+		{"Sequential", []Option{WithAutoTuneThreads(true), WithSequentialSingleThread(true)}, []int{1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := copyFile(t, "testdata/large.bin", "testdata/test/tune.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			f.Close()
+			defer os.Remove("testdata/test/tune.bin")
+			l := &testLogger{}
+			s := NewShredder(append(tt.opts, WithLogger(l))...)
+			stats, err := s.ShredWithStats("testdata/test/tune.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			found := false
+			for _, n := range tt.want {
+				found = found || stats.Threads == n
+			}
+			if !found {
+				t.Fatalf("expected one of %v threads, got %d\n", tt.want, stats.Threads)
+			}
+			if tuned := len(l.msgs[LevelInfo]) > 1; tuned != (tt.opts != nil) {
+				t.Fatalf("expected the tuning logged: %v, got %v\n", tt.opts != nil, l.msgs[LevelInfo])
+			}
+		})
+	}
+	if _, ok := tuned.Load(os.TempDir()); !ok {
+		t.Fatalf("expected the thread count cached\n")
+	}
+}
+
+func TestAutoThreadsWarmUp(t *testing.T) {
+	var mu sync.Mutex
+	overwrites := 0
+	injectFault = func(pass int, off, n int64) error {
+		if off == 0 {
+			mu.Lock()
+			overwrites++
+			mu.Unlock()
+		}
+		return nil
+	}
+	defer func() { injectFault = nil }()
+	if _, err := autoThreads(t.TempDir()); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if overwrites != len(tuneThreads)+1 {
+		t.Fatalf("expected a warm-up before %d timed overwrites, got %d overwrites\n", len(tuneThreads), overwrites)
+	}
+}
@@ -0,0 +1,58 @@
+package tatter
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+)
+
+// State of a file at some point, to make sure ShredIfUnchanged shreds
+// the same file that was inspected earlier. Besides its size and
+// modification time, it identifies the file itself, by inode and device
+// on Unix and by volume and file index on Windows, so a replacement is
+// caught even if it looks identical.
+type Snapshot struct {
+	Size    int64
+	ModTime time.Time
+	info    os.FileInfo
+}
+
+// Captures the state of the regular file at path, without following
+// symlinks.
+func TakeSnapshot(path string) (Snapshot, error) {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return Snapshot{}, err
+	}
+	if !info.Mode().IsRegular() {
+		return Snapshot{}, fmt.Errorf("%w: %s is %v", ErrNotRegularFile, path, info.Mode().Type())
+	}
+	return Snapshot{Size: info.Size(), ModTime: info.ModTime(), info: info}, nil
+}
+
+// Returns ErrChanged if the file described by info is not the one of the
+// snapshot, or its size or modification time differ.
+func (s Snapshot) check(path string, info os.FileInfo) error {
+	switch {
+	case s.info == nil || !os.SameFile(s.info, info):
+		return fmt.Errorf("%w: %s was replaced", ErrChanged, path)
+	case info.Size() != s.Size:
+		return fmt.Errorf("%w: %s is %d bytes, was %d", ErrChanged, path, info.Size(), s.Size)
+	case !info.ModTime().Equal(s.ModTime):
+		return fmt.Errorf("%w: %s was modified at %v", ErrChanged, path, info.ModTime())
+	}
+	return nil
+}
+
+// Same as Shred, but refuses with ErrChanged, before writing anything,
+// unless the file at path is still the one of snap, as returned by
+// TakeSnapshot, with the same size and modification time. The file is
+// compared once opened, so it cannot be replaced after the check, and
+// with WithLock it cannot be modified either.
+func ShredIfUnchanged(path string, snap Snapshot, opts ...Option) error {
+	c := newConfig(opts)
+	c.snapshot = &snap
+	_, err := shred(context.Background(), path, c)
+	return err
+}
@@ -0,0 +1,78 @@
+package tatter
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+type TestSnapshotTable struct {
+	name   string
+	change func(path string) error
+	want   error
+}
+
+func TestShredIfUnchanged(t *testing.T) {
+	var tests = []TestSnapshotTable{
+		{"Unchanged", func(path string) error { return nil }, nil},
+		{"Touched", func(path string) error {
+			return os.Chtimes(path, time.Now(), time.Now().Add(time.Hour))
+		}, ErrChanged},
+		{"Grown", func(path string) error {
+			f, err := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			_, err = f.Write([]byte{0})
+			return err
+		}, ErrChanged},
+		{"Replaced", func(path string) error {
+			info, err := os.Stat(path)
+			if err != nil {
+				return err
+			}
+			// Keeps the size and times, so only the inode tells.
+			if err := os.Rename("testdata/test/snapshot2.bin", path); err != nil {
+				return err
+			}
+			return os.Chtimes(path, info.ModTime(), info.ModTime())
+		}, ErrChanged},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, path := range []string{"testdata/test/snapshot.bin", "testdata/test/snapshot2.bin"} {
+				f, err := copyFile(t, "testdata/large.bin", path)
+				if err != nil {
+					t.Fatalf("err: %v\n", err)
+				}
+				f.Close()
+				defer os.Remove(path)
+			}
+			snap, err := TakeSnapshot("testdata/test/snapshot.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			if err := tt.change("testdata/test/snapshot.bin"); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			err = ShredIfUnchanged("testdata/test/snapshot.bin", snap)
+			if !errors.Is(err, tt.want) {
+				t.Fatalf("got: %v, want %v\n", err, tt.want)
+			}
+			if _, serr := os.Stat("testdata/test/snapshot.bin"); (serr == nil) != (err != nil) {
+				t.Fatalf("expected the file removed only when unchanged, got %v\n", serr)
+			}
+		})
+	}
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/snapshot.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/snapshot.bin")
+	if err := ShredIfUnchanged("testdata/test/snapshot.bin", Snapshot{}); !errors.Is(err, ErrChanged) {
+		t.Fatalf("expected ErrChanged for an empty snapshot, got %v\n", err)
+	}
+}
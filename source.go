@@ -0,0 +1,427 @@
+package tatter
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math/big"
+	"math/bits"
+)
+
+// Provides the data written to the file on a pass. Fill must fill the
+// whole of b with the data meant for offset off of the file. It is
+// called concurrently by the threads of a pass, each one with its own
+// buffer. Buffers are always completely filled before being written,
+// so nothing carries over from a previous pass.
+type PassSource interface {
+	Fill(b []byte, off int64) error
+}
+
+// Fills buffers with data read from R, or from crypto/rand if R is nil.
+type RandomSource struct {
+	R io.Reader
+}
+
+func (s RandomSource) Fill(b []byte, off int64) error {
+	r := s.R
+	if r == nil {
+		r = rand.Reader
+	}
+	_, err := io.ReadFull(r, b)
+	return err
+}
+
+// Fills buffers with the data the file f holds XORed with random data,
+// for the pass of WithXorPass.
+type xorSource struct {
+	RandomSource
+	f io.ReaderAt
+}
+
+func (s xorSource) Fill(b []byte, off int64) error {
+	if err := s.RandomSource.Fill(b, off); err != nil {
+		return err
+	}
+	var old [4096]byte
+	for i := 0; i < len(b); i += len(old) {
+		chunk := old[:]
+		if len(b)-i < len(chunk) {
+			chunk = chunk[:len(b)-i]
+		}
+		if n, err := s.f.ReadAt(chunk, off+int64(i)); n < len(chunk) {
+			return err
+		}
+		for k, v := range chunk {
+			b[i+k] ^= v
+		}
+	}
+	return nil
+}
+
+// Reads a sample of 20000 bits from r, returning ErrWeakRandomSource if
+// they are all the same byte or fail the FIPS 140-2 monobit test.
+func checkRandom(r io.Reader) error {
+	var sample [2500]byte
+	if _, err := io.ReadFull(r, sample[:]); err != nil {
+		return err
+	}
+	same := true
+	ones := 0
+	for _, v := range sample {
+		same = same && v == sample[0]
+		ones += bits.OnesCount8(v)
+	}
+	if same {
+		return fmt.Errorf("%w: every byte of the sample is %#02x", ErrWeakRandomSource, sample[0])
+	}
+	if ones <= 9725 || ones >= 10275 {
+		return fmt.Errorf("%w: %d of 20000 bits set", ErrWeakRandomSource, ones)
+	}
+	return nil
+}
+
+// Fills buffers with a single repeated byte.
+type ConstantSource byte
+
+func (s ConstantSource) Fill(b []byte, off int64) error {
+	for i := range b {
+		b[i] = byte(s)
+	}
+	return nil
+}
+
+// Sources whose data repeats every period bytes from the start of the
+// file, so a pass can fill a single template buffer and write slices of
+// it instead of filling every batch.
+type periodic interface {
+	period() int
+}
+
+// Longest period for which a template is used, to keep its extra
+// memory small.
+const maxPeriod = 4096
+
+func (s ConstantSource) period() int {
+	return 1
+}
+
+func (s PatternSource) period() int {
+	return len(s)
+}
+
+// Fills buffers with a repeated sequence of bytes. The sequence is
+// aligned to the start of the file, so it is continuous across buffers.
+type PatternSource []byte
+
+func (s PatternSource) Fill(b []byte, off int64) error {
+	n := int64(len(s))
+	for i := range b {
+		b[i] = s[(off+int64(i))%n]
+	}
+	return nil
+}
+
+// Fills every byte of the buffers with the value the function returns
+// for its offset in the file, e.g. to write different patterns to
+// different regions in a single pass, as some standards do:
+//
+//	OffsetSource(func(off int64) byte {
+//		if off < size/2 {
+//			return 0x00
+//		}
+//		return 0xFF
+//	})
+//
+// The function is called concurrently by the threads of a pass, once
+// for every byte, so it should be cheap and must be safe for concurrent
+// use.
+type OffsetSource func(off int64) byte
+
+func (s OffsetSource) Fill(b []byte, off int64) error {
+	for i := range b {
+		b[i] = s(off + int64(i))
+	}
+	return nil
+}
+
+// Fills buffers with a counter derived from the offset: every 8 bytes
+// of the file, starting at offset o (a multiple of 8), hold o as a
+// little-endian uint64. Meant for testing recovery tools, since what
+// ends up on disk is known exactly; it does not hide anything.
+type CounterSource struct{}
+
+func (CounterSource) Fill(b []byte, off int64) error {
+	for i := range b {
+		o := uint64(off + int64(i))
+		b[i] = byte((o &^ 7) >> (8 * (o & 7)))
+	}
+	return nil
+}
+
+// Fills buffers with random data, read from R or from crypto/rand if R
+// is nil, except for a fraction Ratio, from 0 to 1, of the blocks of
+// BlockSize bytes, 4096 if it is 0, aligned to the start of the file,
+// which hold Pattern, or zeros if it is empty, repeated as
+// PatternSource does. Whether a block holds the pattern is drawn from
+// Seed and its offset only, so the same blocks get it on every pass and
+// run with the same Seed, e.g. 10% zeros and 90% random data. Meant for
+// stress-testing recovery tools; it is not meant for security, since
+// the pattern blocks hide nothing random data would not.
+type MixSource struct {
+	Ratio     float64
+	Pattern   []byte
+	BlockSize int
+	Seed      int64
+	R         io.Reader
+}
+
+func (s MixSource) Fill(b []byte, off int64) error {
+	if s.Ratio < 0 || s.Ratio > 1 {
+		return fmt.Errorf("mix ratio %v is not between 0 and 1", s.Ratio)
+	}
+	size := int64(s.BlockSize)
+	if size <= 0 {
+		size = defBlock
+	}
+	var pattern PassSource = PatternSource(s.Pattern)
+	if len(s.Pattern) == 0 {
+		pattern = ConstantSource(0)
+	}
+	for len(b) > 0 {
+		block := off / size
+		n := (block+1)*size - off
+		if n > int64(len(b)) {
+			n = int64(len(b))
+		}
+		src := PassSource(RandomSource{s.R})
+		if s.patterned(block) {
+			src = pattern
+		}
+		if err := src.Fill(b[:n], off); err != nil {
+			return err
+		}
+		b, off = b[n:], off+n
+	}
+	return nil
+}
+
+// Reports whether the given block holds the pattern, hashing Seed and
+// the block with SplitMix64 into a number uniform in [0, 1).
+func (s MixSource) patterned(block int64) bool {
+	x := uint64(s.Seed) + uint64(block+1)*0x9E3779B97F4A7C15
+	x = (x ^ x>>30) * 0xBF58476D1CE4E5B9
+	x = (x ^ x>>27) * 0x94D049BB133111EB
+	x ^= x >> 31
+	return float64(x>>11)/(1<<53) < s.Ratio
+}
+
+// Shift between the data of consecutive passes read from a pool, prime
+// so it is never a multiple of a block size.
+const poolShift = 8191
+
+// Fills buffers with the data at the same offset of the size bytes of
+// random data of r, plus shift, wrapping around at its end.
+type poolSource struct {
+	r     io.ReaderAt
+	size  int64
+	shift int64
+}
+
+func (s poolSource) Fill(b []byte, off int64) error {
+	pos := (off + s.shift) % s.size
+	for len(b) > 0 {
+		n := int64(len(b))
+		if n > s.size-pos {
+			n = s.size - pos
+		}
+		if m, err := s.r.ReadAt(b[:n], pos); int64(m) < n {
+			return err
+		}
+		b, pos = b[n:], 0
+	}
+	return nil
+}
+
+// Fills buffers with the data at the same offset of a prefilled buffer
+// holding the content of the whole pass.
+type bufferSource []byte
+
+func (s bufferSource) Fill(b []byte, off int64) error {
+	copy(b, s[off:])
+	return nil
+}
+
+// Implemented by sources that derive a new source for every pass, which
+// is what is used to fill the buffers of that pass.
+type perPass interface {
+	forPass(pass int) (PassSource, error)
+}
+
+// Returns the source that fills the buffers of the given pass.
+func passSource(src PassSource, pass int) (PassSource, error) {
+	if p, ok := src.(perPass); ok {
+		return p.forPass(pass)
+	}
+	return src, nil
+}
+
+// Reports whether src writes the same data on every pass, so that what
+// it wrote is known without recording it.
+func deterministic(src PassSource) bool {
+	switch src.(type) {
+	case ConstantSource, PatternSource, CounterSource:
+		return true
+	}
+	return false
+}
+
+// Returns a copy of srcs in a random order, drawn from crypto/rand
+// with a Fisher-Yates shuffle.
+func shuffled(srcs []PassSource) ([]PassSource, error) {
+	s := append([]PassSource(nil), srcs...)
+	for i := len(s) - 1; i > 0; i-- {
+		j, err := rand.Int(rand.Reader, big.NewInt(int64(i+1)))
+		if err != nil {
+			return nil, err
+		}
+		s[i], s[j.Int64()] = s[j.Int64()], s[i]
+	}
+	return s, nil
+}
+
+// Fills buffers with an AES-256-CTR keystream, which is much cheaper
+// than reading crypto/rand for large files while still being
+// indistinguishable from random data. When used as a pass, the key and
+// IV are drawn from crypto/rand at the start of every pass, so no two
+// passes ever write the same data. Calling Fill directly draws a new
+// key for every call.
+type KeystreamSource struct{}
+
+func (s KeystreamSource) Fill(b []byte, off int64) error {
+	src, err := s.forPass(0)
+	if err != nil {
+		return err
+	}
+	return src.Fill(b, off)
+}
+
+func (s KeystreamSource) forPass(pass int) (PassSource, error) {
+	key := make([]byte, 32+aes.BlockSize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key[:32])
+	if err != nil {
+		return nil, err
+	}
+	return &keystream{block: block, iv: key[32:]}, nil
+}
+
+// Fills buffers with an AES-256-CTR keystream, as KeystreamSource does,
+// except for the first 16 bytes of every block of BlockSize bytes,
+// 4096 if it is 0, aligned to the start of the file: they hold a nonce
+// of 8 bytes, drawn from crypto/rand at the start of every pass, and
+// the offset of the block as a little-endian uint64. No two blocks of a
+// file then hold the same data, nor do blocks of different passes or
+// files, so storage that deduplicates blocks cannot collapse the
+// overwrite into a few physical blocks, leaving the rest of the
+// original data in place. BlockSize should match the deduplication
+// block size of the storage, or divide it, and must be at least 16.
+type UniqueBlockSource struct {
+	BlockSize int
+}
+
+func (s UniqueBlockSource) Fill(b []byte, off int64) error {
+	src, err := s.forPass(0)
+	if err != nil {
+		return err
+	}
+	return src.Fill(b, off)
+}
+
+func (s UniqueBlockSource) forPass(pass int) (PassSource, error) {
+	size := int64(s.BlockSize)
+	if size == 0 {
+		size = 4096
+	}
+	if size < 16 {
+		return nil, fmt.Errorf("unique block size must be at least 16, got %d", size)
+	}
+	ks, err := KeystreamSource{}.forPass(pass)
+	if err != nil {
+		return nil, err
+	}
+	u := &uniqueBlocks{keystream: ks.(*keystream), size: size}
+	if _, err := io.ReadFull(rand.Reader, u.nonce[:]); err != nil {
+		return nil, err
+	}
+	return u, nil
+}
+
+// A keystream with a header of a nonce and the block offset at the
+// start of every block of the given size.
+type uniqueBlocks struct {
+	*keystream
+	nonce [8]byte
+	size  int64
+}
+
+func (u *uniqueBlocks) Fill(b []byte, off int64) error {
+	if err := u.keystream.Fill(b, off); err != nil {
+		return err
+	}
+	var header [16]byte
+	copy(header[:], u.nonce[:])
+	end := off + int64(len(b))
+	for start := off - off%u.size; start < end; start += u.size {
+		binary.LittleEndian.PutUint64(header[8:], uint64(start))
+		for i := int64(0); i < 16; i++ {
+			if pos := start + i; pos >= off && pos < end {
+				b[pos-off] = header[i]
+			}
+		}
+	}
+	return nil
+}
+
+// Returns a keystream with the IV of k, keyed by k and id together: its
+// key is the SHA-256 of id encrypted with k, so its data depends on
+// both and is as random as that of k.
+func (k *keystream) mixed(id []byte) *keystream {
+	h := sha256.Sum256(id)
+	key := make([]byte, 32)
+	k.block.Encrypt(key[:16], h[:16])
+	k.block.Encrypt(key[16:], h[16:])
+	block, _ := aes.NewCipher(key) // never fails with 32 bytes
+	return &keystream{block: block, iv: k.iv}
+}
+
+// A keyed AES-CTR keystream that can be read at any offset, and so by
+// several threads at the same time.
+type keystream struct {
+	block cipher.Block
+	iv    []byte
+}
+
+func (k *keystream) Fill(b []byte, off int64) error {
+	iv := make([]byte, aes.BlockSize)
+	copy(iv, k.iv)
+	ctr := uint64(off / aes.BlockSize)
+	for i := aes.BlockSize - 1; i >= 0 && ctr > 0; i-- {
+		sum := uint64(iv[i]) + ctr&0xFF
+		iv[i] = byte(sum)
+		ctr = ctr>>8 + sum>>8
+	}
+	stream := cipher.NewCTR(k.block, iv)
+	skip := make([]byte, off%aes.BlockSize)
+	stream.XORKeyStream(skip, skip)
+	for i := range b {
+		b[i] = 0
+	}
+	stream.XORKeyStream(b, b)
+	return nil
+}
@@ -0,0 +1,452 @@
+package tatter
+
+import (
+	"bytes"
+	"context"
+	"crypto/aes"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"testing"
+	"testing/iotest"
+)
+
+type TestSourceTable struct {
+	name string
+	src  PassSource
+	off  int64
+	want []byte
+}
+
+func TestSourceFill(t *testing.T) {
+	var tests = []TestSourceTable{
+		{"Constant", ConstantSource(0xFF), 0, []byte{0xFF, 0xFF, 0xFF, 0xFF}},
+		{"Pattern", PatternSource{1, 2, 3}, 0, []byte{1, 2, 3, 1}},
+		{"PatternOffset", PatternSource{1, 2, 3}, 4, []byte{2, 3, 1, 2}},
+		{"Offset", OffsetSource(func(off int64) byte { return byte(off / 2) }), 5, []byte{2, 3, 3, 4}},
+		{"Counter", CounterSource{}, 0, []byte{0, 0, 0, 0, 0, 0, 0, 0, 8, 0, 0, 0, 0, 0, 0, 0, 16}},
+		{"CounterOffset", CounterSource{}, 0x10202, []byte{0x01, 0, 0, 0, 0, 0, 0x08, 0x02, 0x01, 0}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := make([]byte, len(tt.want))
+			if err := tt.src.Fill(b, tt.off); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			if !bytes.Equal(b, tt.want) {
+				t.Fatalf("expected %v, got %v\n", tt.want, b)
+			}
+		})
+	}
+}
+
+func TestRandomSourceFill(t *testing.T) {
+	b := make([]byte, 64)
+	if err := (RandomSource{}).Fill(b, 0); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if bytes.Equal(b, make([]byte, 64)) {
+		t.Fatalf("random buffer is all zeros\n")
+	}
+	if err := (RandomSource{iotest.ErrReader(errors.New("Rand err"))}).Fill(b, 0); err == nil {
+		t.Fatalf("expected rand err, got nil\n")
+	}
+}
+
+func TestXorSourceFill(t *testing.T) {
+	old := make([]byte, 10100)
+	random := make([]byte, 10000)
+	for i := range old {
+		old[i] = byte(i)
+	}
+	for i := range random {
+		random[i] = byte(i * 7)
+	}
+	src := xorSource{RandomSource{bytes.NewReader(random)}, bytes.NewReader(old)}
+	b := make([]byte, 10000)
+	if err := src.Fill(b, 100); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	for i, v := range b {
+		if want := old[100+i] ^ random[i]; v != want {
+			t.Fatalf("byte %d is %#x, want %#x\n", i, v, want)
+		}
+	}
+	if err := src.Fill(b[:1], 10100); err == nil {
+		t.Fatalf("expected err reading past the end, got nil\n")
+	}
+}
+
+func TestWithPassSourcesEmpty(t *testing.T) {
+	path := "testdata/test/nosources.bin"
+	f, err := copyFile(t, "testdata/small.bin", path)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove(path)
+	if err := Shred(path, WithPassSources()); err == nil {
+		t.Fatalf("expected err, got nil\n")
+	}
+}
+
+func TestKeystreamOffsets(t *testing.T) {
+	src, err := KeystreamSource{}.forPass(0)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	whole := make([]byte, 1000)
+	if err := src.Fill(whole, 0); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	for _, off := range []int64{1, 15, 16, 17, 300, 999} {
+		part := make([]byte, 1000-off)
+		if err := src.Fill(part, off); err != nil {
+			t.Fatalf("err: %v\n", err)
+		}
+		if !bytes.Equal(part, whole[off:]) {
+			t.Fatalf("keystream at offset %d does not match\n", off)
+		}
+	}
+	if bytes.Equal(whole[:16], make([]byte, 16)) {
+		t.Fatalf("keystream is all zeros\n")
+	}
+}
+
+func TestKeystreamCounterCarry(t *testing.T) {
+	src := &keystream{iv: bytes.Repeat([]byte{0xFF}, 16)}
+	src.iv[0] = 0x00
+	block, err := aes.NewCipher(make([]byte, 32))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	src.block = block
+	whole := make([]byte, 64)
+	src.Fill(whole, 0)
+	part := make([]byte, 32)
+	src.Fill(part, 32)
+	if !bytes.Equal(part, whole[32:]) {
+		t.Fatalf("keystream counter does not carry\n")
+	}
+}
+
+func TestKeystreamDistinctPasses(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/keystream.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/keystream.bin")
+	defer f.Close()
+	var mu sync.Mutex
+	firsts := make(map[string]int)
+	record := WithBufferTransform(func(buf []byte, pass int) {
+		mu.Lock()
+		firsts[string(buf[:16])]++
+		mu.Unlock()
+	})
+	opts := []Option{WithPassSources(KeystreamSource{}, KeystreamSource{}, KeystreamSource{}), record}
+	if err := shredFile(context.Background(), f, newConfig(opts), &ShredStats{}); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	for first, n := range firsts {
+		if n > 1 {
+			t.Fatalf("buffer starting with %x written %d times\n", first, n)
+		}
+	}
+}
+
+func TestShuffled(t *testing.T) {
+	srcs := []PassSource{ConstantSource(0), ConstantSource(1), ConstantSource(2), ConstantSource(3)}
+	moved := false
+	for i := 0; i < 20; i++ {
+		s, err := shuffled(srcs)
+		if err != nil {
+			t.Fatalf("err: %v\n", err)
+		}
+		seen := make(map[PassSource]bool)
+		for j, src := range s {
+			seen[src] = true
+			if src != srcs[j] {
+				moved = true
+			}
+		}
+		if len(seen) != len(srcs) {
+			t.Fatalf("expected a permutation of %v, got %v\n", srcs, s)
+		}
+	}
+	if !moved {
+		t.Fatalf("20 shuffles kept the original order\n")
+	}
+	if srcs[0] != ConstantSource(0) || srcs[3] != ConstantSource(3) {
+		t.Fatalf("shuffled modified the given slice: %v\n", srcs)
+	}
+}
+
+func TestDeterministic(t *testing.T) {
+	srcs := []PassSource{ConstantSource(0), PatternSource{1, 2}, CounterSource{}, RandomSource{}, KeystreamSource{}}
+	want := []bool{true, true, true, false, false}
+	for i, src := range srcs {
+		if got := deterministic(src); got != want[i] {
+			t.Fatalf("%T: expected %v, got %v\n", src, want[i], got)
+		}
+	}
+}
+
+func TestOffsetSourceRegions(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/regions.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/regions.bin")
+	defer f.Close()
+	halves := OffsetSource(func(off int64) byte {
+		if off < 40716/2 {
+			return 0x00
+		}
+		return 0xFF
+	})
+	if err := shredFile(context.Background(), f, newConfig([]Option{WithPassSources(halves)}), &ShredStats{}); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	b := make([]byte, 40716)
+	if _, err := f.ReadAt(b, 0); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	for i, v := range b {
+		if v != halves(int64(i)) {
+			t.Fatalf("byte %d is %#x, want %#x\n", i, v, halves(int64(i)))
+		}
+	}
+}
+
+type TestTemplateTable struct {
+	name    string
+	src     PassSource
+	off     int64
+	bufSize int64
+}
+
+func TestTemplate(t *testing.T) {
+	var tests = []TestTemplateTable{
+		{"Constant", ConstantSource(0xAA), 13, 1000},
+		{"Pattern", PatternSource{0x92, 0x49, 0x24}, 13, 1000},
+		{"PatternAligned", PatternSource{0x01, 0x02}, 0, 1024},
+		{"PatternOdd", PatternSource{0x01, 0x02, 0x03, 0x04, 0x05}, 7, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &corruptFile{b: make([]byte, 10000), corrupt: -1}
+			res := make(chan procResult, 1)
+			shredProc(context.Background(), f, tt.off, 10000-tt.off, tt.bufSize, tt.src, 0, newConfig(nil), nil, res)
+			if r := <-res; r.Err != nil {
+				t.Fatalf("err: %v\n", r.Err)
+			}
+			want := make([]byte, 10000)
+			if err := tt.src.Fill(want, 0); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			if !bytes.Equal(f.b[tt.off:], want[tt.off:]) {
+				t.Fatalf("template batches differ from the filled ones\n")
+			}
+		})
+	}
+}
+
+type TestPoolSourceTable struct {
+	name  string
+	off   int64
+	n     int
+	shift int64
+}
+
+func TestPoolSourceFill(t *testing.T) {
+	pool := make([]byte, 100)
+	for i := range pool {
+		pool[i] = byte(i)
+	}
+	var tests = []TestPoolSourceTable{
+		{"Inside", 10, 50, 0},
+		{"End", 30, 70, 0},
+		{"Wrapping", 30, 250, 0},
+		{"Shifted", 0, 150, poolShift},
+		{"Beyond", 1234, 10, poolShift},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			b := make([]byte, tt.n)
+			src := poolSource{bytes.NewReader(pool), 100, tt.shift}
+			if err := src.Fill(b, tt.off); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			for i, v := range b {
+				if want := pool[(tt.off+int64(i)+tt.shift)%100]; v != want {
+					t.Fatalf("byte %d is %d, want %d\n", i, v, want)
+				}
+			}
+		})
+	}
+}
+
+type TestCheckRandomTable struct {
+	name string
+	r    io.Reader
+	want error
+}
+
+func TestCheckRandom(t *testing.T) {
+	var tests = []TestCheckRandomTable{
+		{"Crypto", rand.Reader, nil},
+		{"Zeros", bytes.NewReader(make([]byte, 2500)), ErrWeakRandomSource},
+		{"Same", bytes.NewReader(bytes.Repeat([]byte{0x5A}, 2500)), ErrWeakRandomSource},
+		{"Biased", bytes.NewReader(bytes.Repeat([]byte{0x01, 0x03}, 1250)), ErrWeakRandomSource},
+		{"Short", bytes.NewReader(make([]byte, 10)), io.ErrUnexpectedEOF},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := checkRandom(tt.r); !errors.Is(err, tt.want) {
+				t.Fatalf("got: %v, want %v\n", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestUniqueBlockSource(t *testing.T) {
+	src, err := passSource(UniqueBlockSource{}, 0)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	whole := make([]byte, 1<<20)
+	if err := src.Fill(whole, 0); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	seen := make(map[string]int64)
+	for off := int64(0); off < int64(len(whole)); off += 4096 {
+		block := string(whole[off : off+4096])
+		if prev, ok := seen[block]; ok {
+			t.Fatalf("blocks at %d and %d are identical\n", prev, off)
+		}
+		seen[block] = off
+	}
+	// Batches crossing block boundaries at any offset match.
+	for off := int64(5); off < 20000; off += 1234 {
+		b := make([]byte, 1000)
+		if err := src.Fill(b, off); err != nil {
+			t.Fatalf("err: %v\n", err)
+		}
+		if !bytes.Equal(b, whole[off:off+1000]) {
+			t.Fatalf("batch at %d differs from the whole fill\n", off)
+		}
+	}
+	if _, err := passSource(UniqueBlockSource{BlockSize: 8}, 0); err == nil {
+		t.Fatalf("expected block size err, got nil\n")
+	}
+}
+
+// Returns the indexes of the blocks of b that hold nothing but c.
+func patternBlocks(b []byte, size int, c byte) []int {
+	var blocks []int
+	for i := 0; i < len(b); i += size {
+		if bytes.Equal(b[i:i+size], bytes.Repeat([]byte{c}, size)) {
+			blocks = append(blocks, i/size)
+		}
+	}
+	return blocks
+}
+
+type TestMixSourceTable struct {
+	name     string
+	src      MixSource
+	min, max int
+}
+
+func TestMixSource(t *testing.T) {
+	var tests = []TestMixSourceTable{
+		{"Random", MixSource{Ratio: 0, Pattern: []byte{0xab}}, 0, 0},
+		{"Pattern", MixSource{Ratio: 1, Pattern: []byte{0xab}}, 1000, 1000},
+		{"Tenth", MixSource{Ratio: 0.1, Pattern: []byte{0xab}, Seed: 42}, 50, 150},
+		{"Half", MixSource{Ratio: 0.5, Pattern: []byte{0xab}, BlockSize: 512, Seed: 7}, 400, 600},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			size := tt.src.BlockSize
+			if size == 0 {
+				size = 4096
+			}
+			b := make([]byte, 1000*size)
+			if err := tt.src.Fill(b, 0); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			blocks := patternBlocks(b, size, 0xab)
+			if len(blocks) < tt.min || len(blocks) > tt.max {
+				t.Fatalf("expected %d to %d pattern blocks, got %d\n", tt.min, tt.max, len(blocks))
+			}
+			// The same seed picks the same blocks, however the buffers
+			// are cut.
+			again := make([]byte, len(b))
+			for off := 0; off < len(again); off += 3000 {
+				end := off + 3000
+				if end > len(again) {
+					end = len(again)
+				}
+				if err := tt.src.Fill(again[off:end], int64(off)); err != nil {
+					t.Fatalf("err: %v\n", err)
+				}
+			}
+			if got := patternBlocks(again, size, 0xab); fmt.Sprint(got) != fmt.Sprint(blocks) {
+				t.Fatalf("expected pattern blocks %v, got %v\n", blocks, got)
+			}
+		})
+	}
+	if err := (MixSource{Ratio: 1.5}).Fill(make([]byte, 10), 0); err == nil {
+		t.Fatalf("expected ratio err, got nil\n")
+	}
+}
+
+type TestWithHostMixTable struct {
+	name string
+	src  PassSource
+}
+
+func TestWithHostMix(t *testing.T) {
+	var tests = []TestWithHostMixTable{
+		{"Keystream", KeystreamSource{}},
+		{"UniqueBlock", UniqueBlockSource{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			src, err := passSource(tt.src, 0)
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			fill := func(src PassSource) []byte {
+				b := make([]byte, 8192)
+				if err := src.Fill(b, 0); err != nil {
+					t.Fatalf("err: %v\n", err)
+				}
+				return b
+			}
+			plain := fill(src)
+			a := fill(newConfig([]Option{WithHostMix([]byte("host-a"))}).random(src, 0))
+			again := fill(newConfig([]Option{WithHostMix([]byte("host-a"))}).random(src, 0))
+			b := fill(newConfig([]Option{WithHostMix([]byte("host-b"))}).random(src, 0))
+			if bytes.Equal(a, plain) || bytes.Equal(a, b) || !bytes.Equal(a, again) {
+				t.Fatalf("expected the data to depend on the host id only\n")
+			}
+			if _, ok := tt.src.(UniqueBlockSource); ok && !bytes.Equal(a[:16], plain[:16]) {
+				t.Fatalf("expected the block header kept, got %x\n", a[:16])
+			}
+		})
+	}
+	for _, id := range [][]byte{nil, make([]byte, maxHostID+1)} {
+		if err := newConfig([]Option{WithHostMix(id)}).validate(); err == nil {
+			t.Fatalf("expected %d bytes host id refused\n", len(id))
+		}
+	}
+	// Other sources are left as they are.
+	if src := newConfig([]Option{WithHostMix([]byte("host-a"))}).random(ConstantSource(0), 0); src != ConstantSource(0) {
+		t.Fatalf("expected ConstantSource kept, got %T\n", src)
+	}
+}
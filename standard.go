@@ -0,0 +1,73 @@
+package tatter
+
+import "fmt"
+
+// Well known overwrite methods, each one mapping to a sequence of passes.
+type Standard int
+
+const (
+	StandardRandom3 Standard = iota // 3 random passes, the default
+	StandardQuick                   // 1 pass of zeros
+	StandardDoD3                    // DoD 5220.22-M: zeros, ones, random
+	StandardDoD7                    // DoD 5220.22-M ECE: DoD3, random, DoD3
+	StandardGutmann                 // Gutmann's 35 passes
+)
+
+func (s Standard) String() string {
+	switch s {
+	case StandardRandom3:
+		return "random3"
+	case StandardQuick:
+		return "quick"
+	case StandardDoD3:
+		return "dod3"
+	case StandardDoD7:
+		return "dod7"
+	case StandardGutmann:
+		return "gutmann"
+	}
+	return fmt.Sprintf("Standard(%d)", int(s))
+}
+
+// Returns the sequence of passes of the standard.
+func (s Standard) sources() ([]PassSource, error) {
+	switch s {
+	case StandardRandom3:
+		return []PassSource{RandomSource{}, RandomSource{}, RandomSource{}}, nil
+	case StandardQuick:
+		return []PassSource{ConstantSource(0x00)}, nil
+	case StandardDoD3:
+		return []PassSource{ConstantSource(0x00), ConstantSource(0xFF), RandomSource{}}, nil
+	case StandardDoD7:
+		dod3 := []PassSource{ConstantSource(0x00), ConstantSource(0xFF), RandomSource{}}
+		return append(append(append([]PassSource{}, dod3...), RandomSource{}), dod3...), nil
+	case StandardGutmann:
+		return gutmann(), nil
+	}
+	return nil, fmt.Errorf("unknown standard: %v", s)
+}
+
+// Builds the 35 passes of the Gutmann method: 4 random passes, the 27
+// patterns targeting MFM/RLL encodings, and 4 more random passes.
+func gutmann() []PassSource {
+	srcs := []PassSource{RandomSource{}, RandomSource{}, RandomSource{}, RandomSource{}}
+	srcs = append(srcs,
+		ConstantSource(0x55),
+		ConstantSource(0xAA),
+		PatternSource{0x92, 0x49, 0x24},
+		PatternSource{0x49, 0x24, 0x92},
+		PatternSource{0x24, 0x92, 0x49},
+	)
+	for b := 0x00; b <= 0xFF; b += 0x11 {
+		srcs = append(srcs, ConstantSource(b))
+	}
+	srcs = append(srcs,
+		PatternSource{0x92, 0x49, 0x24},
+		PatternSource{0x49, 0x24, 0x92},
+		PatternSource{0x24, 0x92, 0x49},
+		PatternSource{0x6D, 0xB6, 0xDB},
+		PatternSource{0xB6, 0xDB, 0x6D},
+		PatternSource{0xDB, 0x6D, 0xB6},
+	)
+	return append(srcs, RandomSource{}, RandomSource{}, RandomSource{}, RandomSource{})
+}
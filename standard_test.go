@@ -0,0 +1,82 @@
+package tatter
+
+import (
+	"context"
+	"os"
+	"testing"
+)
+
+type TestStandardTable struct {
+	std    Standard
+	name   string
+	passes int
+}
+
+func TestStandardSources(t *testing.T) {
+	var tests = []TestStandardTable{
+		{StandardRandom3, "random3", 3},
+		{StandardQuick, "quick", 1},
+		{StandardDoD3, "dod3", 3},
+		{StandardDoD7, "dod7", 7},
+		{StandardGutmann, "gutmann", 35},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.std.String() != tt.name {
+				t.Fatalf("expected %s, got %s\n", tt.name, tt.std)
+			}
+			srcs, err := tt.std.sources()
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			if len(srcs) != tt.passes {
+				t.Fatalf("expected %d passes, got %d\n", tt.passes, len(srcs))
+			}
+		})
+	}
+}
+
+func TestStandardUnknown(t *testing.T) {
+	std := Standard(42)
+	if std.String() != "Standard(42)" {
+		t.Fatalf("expected Standard(42), got %s\n", std)
+	}
+	path := "testdata/test/unknown.bin"
+	f, err := copyFile(t, "testdata/small.bin", path)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove(path)
+	defer f.Close()
+	if err := Shred(path, WithStandard(std)); err == nil {
+		t.Fatalf("expected unknown standard err, got nil\n")
+	}
+	if !patternIn(t, "Small123", f) {
+		t.Fatalf("file has been overwritten\n")
+	}
+}
+
+func TestStandardQuick(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/quick.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/quick.bin")
+	defer f.Close()
+	stats := ShredStats{}
+	if err := shredFile(context.Background(), f, newConfig([]Option{WithStandard(StandardQuick)}), &stats); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if stats.Passes != 1 || stats.BytesOverwritten != 3150 {
+		t.Fatalf("unexpected stats %+v\n", stats)
+	}
+	b := make([]byte, 3150)
+	if _, err := f.ReadAt(b, 0); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	for i, v := range b {
+		if v != 0 {
+			t.Fatalf("byte %d is %#x, want 0\n", i, v)
+		}
+	}
+}
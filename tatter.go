@@ -1,16 +1,86 @@
 package tatter
 
 import (
-	"crypto/rand"
+	"bytes"
+	"context"
+	"crypto/sha256"
 	"errors"
+	"fmt"
+	"hash"
 	"io"
 	"os"
+	"path/filepath"
+	"sync"
+	"time"
 )
 
 const bufDef int64 = 4096
 const maxBuf int64 = 64 * 1024 * 1024 // 64MiB
 const diskWrites = 64
 const threads = 3
+const workers = 4
+const defBlock int64 = 4096 // block size assumed when it is unknown
+const invalidateSize int64 = 64 << 10
+const writeRetries = 100 // for writes interrupted with EINTR or EAGAIN
+
+// Removes the file once shreded, replaced in tests to simulate failures.
+var remove = os.Remove
+
+// Returns the path of the running executable, replaced in tests.
+var executable = os.Executable
+
+// Returns the current time, replaced in tests to control the clock.
+var now = time.Now
+
+// Finds the encrypted volume holding a file, replaced in tests.
+var encryptedVolume = cryptVolume
+
+// Test-only fault injection, nil otherwise: called before every write
+// of a pass with its index, or -1 for the quick invalidation before the
+// passes, and the offset and length of the write. When it returns an
+// error, the write fails with it, writing nothing. Called concurrently
+// by the threads of a pass.
+var injectFault func(pass int, off, n int64) error
+
+// Summary of a single shred operation.
+// HardLinkCount is the number of names the file had. When it is over 1,
+// the content was overwritten for every name, but removing path only
+// drops one of them: the file and its (shreded) data remain reachable
+// through the others, so its blocks are not freed.
+// Filesystem is the lowercase name of the filesystem holding the file,
+// e.g. "ext4" or "btrfs", or empty if it could not be determined.
+// Threads is how many threads every pass was split into, although small
+// files may get fewer.
+// Inode is the inode number the file had, taken from its stat when it
+// was opened, e.g. to correlate the shred with filesystem logs, or with
+// WithRecreateSmall that of the file recreated in its place. It is
+// best-effort: it is 0 on Windows, and for files of a FileSystem that
+// does not report it.
+// Encrypted names the encrypted volume found holding the file with
+// WithEncryptionAware, e.g. "dm-crypt luks-root", which reduced its
+// passes to one, or is empty if none was found.
+type ShredStats struct {
+	Path             string
+	Size             int64
+	BytesOverwritten int64
+	Passes           int
+	Duration         time.Duration
+	HardLinkCount    int
+	Filesystem       string
+	OpenByOthers     int
+	BufferReduced    bool
+	Threads          int
+	Inode            uint64
+	Encrypted        string
+}
+
+// Outcome of shredding one of the paths given to ShredAll or found by
+// ShredDir. Skipped paths were left untouched on purpose.
+type Result struct {
+	Stats   ShredStats
+	Err     error
+	Skipped bool
+}
 
 // Calculates the buffer size that is going to be used to write to disk.
 // The buffer size increases with file size to increase performance.
@@ -32,52 +102,477 @@ func calcBuf(fileSize int64) int64 {
 	return bufSize
 }
 
-// Shreds file, overwriting its content using the given rand source,
-// until a given size, writing in batches of the given buffer size.
-// Errors are sent through a channel.
-func shredProc(f *os.File, size int64, bufSize int64, randSrc interface{ io.Reader }, errs chan error) {
+// Outcome of a shredProc: the partition it was given, starting at
+// Offset, how many bytes of it were written and the error that stopped
+// it, if any. Bytes is accurate even on failure, so Offset+Bytes is
+// where the process stopped.
+type procResult struct {
+	Offset, Bytes int64
+	Err           error
+}
+
+// Shreds file, overwriting size bytes of its content from offset off
+// with data from the given pass source, writing in batches of the given
+// buffer size. Periodic sources fill a template buffer once, and every
+// batch is a slice of it, unless the buffer transform from the config
+// is set, which is then applied to every batch as part of the given
+// pass, so batches are filled one by one. A shared buffer holding the
+// whole pass is not copied, every batch is a slice of it at its offset.
+// If rec is not nil, every batch is handed to it before being written.
+// With read after write, batches of deterministic passes are read back
+// as soon as they are written. The process stops between batches once
+// ctx is done. The result is sent through a channel.
+func shredProc(ctx context.Context, f target, off, size int64, bufSize int64, src PassSource, pass int, c *config, rec recorder, res chan procResult) {
+	if c.lowPriority {
+		defer lowerPriority()()
+	}
+	r := procResult{Offset: off}
 	if f == nil {
-		errs <- errors.New("file is nil")
+		r.Err = errors.New("file is nil")
+		res <- r
 		return
 	}
 	if bufSize < 1 {
-		errs <- errors.New("buffer must be greater than 0")
+		r.Err = errors.New("buffer must be greater than 0")
+		res <- r
 		return
 	}
 	rem := size % bufSize
-	b := make([]byte, bufSize)
+	var b, tmpl []byte
+	var period int64
+	shared, _ := src.(bufferSource) // written from directly, taking no more memory
+	if p, ok := src.(periodic); ok && c.transform == nil && p.period() >= 1 && p.period() <= maxPeriod {
+		// Filled once, every batch is then a slice of it at its phase.
+		period = int64(p.period())
+		tmpl = make([]byte, bufSize+period-1)
+		if r.Err = src.Fill(tmpl, 0); r.Err != nil {
+			res <- r
+			return
+		}
+	} else if shared == nil {
+		b = make([]byte, bufSize)
+	}
+	var check []byte
+	if c.readAfterWrite && deterministic(src) {
+		check = make([]byte, bufSize)
+	}
 	sz := bufSize
 	var j int64
 	for j = 0; j < size; j += bufSize {
+		if r.Err = ctx.Err(); r.Err != nil {
+			break
+		}
 		if bufSize+j > size {
 			sz = rem
 		}
-		if _, err := randSrc.Read(b[:sz]); err != nil { // b[:sz] when slicing from right O(1)
-			errs <- err
-			return
+		var buf []byte
+		if shared != nil {
+			buf = shared[off+j : off+j+sz]
+		} else if tmpl != nil {
+			phase := (off + j) % period
+			buf = tmpl[phase : phase+sz]
+		} else {
+			buf = b[:sz] // b[:sz] when slicing from right O(1)
+			if r.Err = src.Fill(buf, off+j); r.Err != nil {
+				break
+			}
 		}
-		if _, err := f.WriteAt(b[:sz], j); err != nil {
-			errs <- err
-			return
+		if c.transform != nil {
+			c.transform(buf, pass)
+		}
+		if rec != nil {
+			rec.record(buf, off+j)
+		}
+		var start time.Time
+		if c.trace != nil {
+			start = now()
+		}
+		n, err := writePass(f, buf, off+j, pass)
+		if c.trace != nil {
+			c.trace(off+j, int64(n), now().Sub(start))
+		}
+		r.Bytes += int64(n)
+		if c.progress != nil && n > 0 {
+			c.progress(pass, off+j, int64(n))
+		}
+		if r.Err = err; r.Err != nil {
+			break
+		}
+		if check != nil {
+			if r.Err = readBack(f, buf, check[:sz], off+j, pass); r.Err != nil {
+				break
+			}
+		}
+		if c.logs(LevelDebug) { // skip formatting every buffer otherwise
+			c.logf(LevelDebug, "%s: pass %d wrote %d bytes at offset %d", f.Name(), pass, n, off+j)
+		}
+	}
+	res <- r
+}
+
+// Writes b at off in f for the given pass, or -1 for the quick
+// invalidation before the passes, going through the fault injection of
+// tests first.
+func writePass(f io.WriterAt, b []byte, off int64, pass int) (int, error) {
+	if injectFault != nil {
+		if err := injectFault(pass, off, int64(len(b))); err != nil {
+			return 0, err
+		}
+	}
+	return writeAt(f, b, off)
+}
+
+// Writes b at off in f, retrying the rest of it when a signal interrupts
+// the write with EINTR or EAGAIN, which the runtime retries for most
+// writes, but not for all of them, e.g. with O_DIRECT or O_SYNC on some
+// systems. Fails with ErrWriteInterrupted after writeRetries retries in
+// a row.
+func writeAt(f io.WriterAt, b []byte, off int64) (int, error) {
+	var written, retries int
+	for {
+		n, err := f.WriteAt(b[written:], off+int64(written))
+		written += n
+		if !retryable(err) {
+			return written, err
+		}
+		if n > 0 {
+			retries = 0
+		}
+		if retries++; retries > writeRetries {
+			return written, fmt.Errorf("%w: %d retries: %w", ErrWriteInterrupted, writeRetries, err)
+		}
+	}
+}
+
+// The file overwritten by the passes. Implemented by *os.File, and by
+// fakes in tests.
+type target interface {
+	io.ReaderAt
+	io.WriterAt
+	Name() string
+}
+
+// Observes the data written by shredProc along with its offset.
+type recorder interface {
+	record(b []byte, off int64)
+}
+
+// Feeds the written data to a hash, regardless of its offset.
+type hashRecorder struct {
+	hash.Hash
+}
+
+func (h hashRecorder) record(b []byte, off int64) {
+	h.Write(b)
+}
+
+// Hands the written data to every one of the recorders.
+type recorders []recorder
+
+func (rs recorders) record(b []byte, off int64) {
+	for _, r := range rs {
+		r.record(b, off)
+	}
+}
+
+// Reads back into scratch the data just written from b at offset off
+// by the given pass and compares both, returning a *VerificationError
+// for the first byte that differs.
+func readBack(f io.ReaderAt, b, scratch []byte, off int64, pass int) error {
+	if _, err := f.ReadAt(scratch, off); err != nil {
+		return err
+	}
+	return compare(b, scratch, off, pass)
+}
+
+// Compares the data a pass wrote at offset off with the data found
+// there, returning a *VerificationError for the first byte that
+// differs.
+func compare(want, got []byte, off int64, pass int) error {
+	for i := range want {
+		if want[i] != got[i] {
+			return &VerificationError{Pass: pass, Offset: off + int64(i), Expected: want[i], Found: got[i]}
+		}
+	}
+	return nil
+}
+
+// Reads back the whole file, of the given size, and compares it with
+// what the deterministic source src wrote on the given pass. Returns a
+// *VerificationError for the first byte that differs.
+func findMismatch(f io.ReaderAt, size int64, src PassSource, pass int) error {
+	want := make([]byte, bufDef)
+	got := make([]byte, bufDef)
+	for off := int64(0); off < size; off += bufDef {
+		n := bufDef
+		if size-off < n {
+			n = size - off
+		}
+		if err := src.Fill(want[:n], off); err != nil {
+			return err
 		}
+		if _, err := f.ReadAt(got[:n], off); err != nil {
+			return err
+		}
+		if err := compare(want[:n], got[:n], off, pass); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Splits size bytes into at most n contiguous partitions, each one a
+// multiple of bufSize except for the last. Returns the offsets where
+// each partition starts, plus size as the final element.
+func partition(size, bufSize int64, n int) []int64 {
+	bufs := (size + bufSize - 1) / bufSize
+	per := (bufs + int64(n) - 1) / int64(n)
+	bounds := []int64{0}
+	for off := per * bufSize; off < size; off += per * bufSize {
+		bounds = append(bounds, off)
+	}
+	return append(bounds, size)
+}
+
+// Runs a single pass over the file, splitting it between the threads,
+// or shards, of the config, each one overwriting its own partition in
+// ascending order. Returns the partition bounds, if hashed is set the
+// hash of the data written to each one of them, and the amount of bytes
+// written by all threads, which is accurate even when the pass failed
+// or was cancelled. If samples is not nil, it records the data written
+// at its offsets. If from is not nil, it holds the offset each
+// partition resumes from.
+func shredPass(ctx context.Context, f target, size, bufSize int64, src PassSource, pass int, c *config, hashed bool, samples *sampler, from []int64) ([]int64, []hash.Hash, int64, error) {
+	bounds := partition(size, bufSize, c.nthreads())
+	if from != nil && len(from) != len(bounds)-1 {
+		return bounds, nil, 0, errors.New("resumed partitions do not match")
+	}
+	var hashes []hash.Hash
+	res := make(chan procResult)
+	for i := 0; i < len(bounds)-1; i++ {
+		var rec recorders
+		if hashed {
+			h := sha256.New()
+			hashes = append(hashes, h)
+			rec = append(rec, hashRecorder{h})
+		}
+		if samples != nil {
+			rec = append(rec, samples)
+		}
+		var r recorder
+		if len(rec) > 0 {
+			r = rec
+		}
+		start := bounds[i]
+		if from != nil {
+			start = from[i]
+		}
+		go shredProc(ctx, f, start, bounds[i+1]-start, bufSize, src, pass, c, r, res)
 	}
-	errs <- nil
+	var written int64
+	var errs []*ShredError
+	for i := 0; i < len(bounds)-1; i++ {
+		r := <-res
+		written += r.Bytes
+		if r.Err != nil {
+			err := r.Err
+			if isDiskFull(err) {
+				err = fmt.Errorf("%w: %w", ErrDiskFull, err)
+			}
+			errs = append(errs, &ShredError{Pass: pass, Offset: r.Offset + r.Bytes, Size: size, Err: err})
+		}
+	}
+	combined := make([]error, len(errs))
+	for i, e := range errs {
+		e.Written = written
+		combined[i] = e
+	}
+	return bounds, hashes, written, c.combine(combined)
+}
+
+// Reads back every partition of the file and compares its hash with
+// the one recorded while it was written.
+func verifyHashes(f io.ReaderAt, bounds []int64, hashes []hash.Hash) error {
+	for i, h := range hashes {
+		got := sha256.New()
+		r := io.NewSectionReader(f, bounds[i], bounds[i+1]-bounds[i])
+		if _, err := io.Copy(got, r); err != nil {
+			return err
+		}
+		if !bytes.Equal(got.Sum(nil), h.Sum(nil)) {
+			return fmt.Errorf("%w: partition at offset %d", ErrVerificationFailed, bounds[i])
+		}
+	}
+	return nil
 }
 
-// Shreds file, overwriting its content given const threads times
-// with random data. Uses n threads, each one overwriting the file once.
-func shredFile(f *os.File) error {
+// Shreds file, overwriting its content once for every pass source of
+// the config. Every pass draws its own randomness: random sources read
+// fresh data for every batch, and keystream sources are keyed anew, so
+// passes are independent of each other. Passes run one after the
+// other, and each one of them is split between const threads goroutines
+// (one with WithSequentialSingleThread) writing disjoint parts of the
+// file, and synced as the sync policy says. With pass diagnostics,
+// every pass is synced and a sample of its data read back before the
+// next one starts, to detect writes coalesced or dropped by the OS.
+// With paranoid verification, the last pass is synced and read back to
+// check that the storage kept exactly what was written.
+// Completed passes and overwritten bytes are accounted in stats as they
+// happen, so they are accurate up to the point of a failure or of ctx
+// being cancelled.
+func shredFile(ctx context.Context, f File, c *config, stats *ShredStats) error {
+	if f == nil {
+		return errors.New("file is nil")
+	}
 	stat, err := f.Stat()
 	if err != nil {
 		return err
 	}
-	bufSize := calcBuf(stat.Size())
-	errors := make(chan error)
-	for i := 0; i < threads; i++ {
-		go shredProc(f, stat.Size(), bufSize, rand.Reader, errors)
+	size, err := c.extent(f, stat)
+	if err != nil {
+		return err
+	}
+	bufSize := c.bufSize(size)
+	if want := c.wantedBuf(size); bufSize < want {
+		stats.BufferReduced = true
+		c.warnReduced(want, bufSize)
+	}
+	if c.sectorAlign {
+		bufSize = c.alignBuf(bufSize, blockSize(f))
 	}
-	for i := 0; i < threads; i++ {
-		if err = <-errors; err != nil {
+	sources := c.sources
+	if c.shuffle {
+		if sources, err = shuffled(sources); err != nil {
+			return err
+		}
+	}
+	if c.xorPass { // first, so it XORs the original content
+		sources = append([]PassSource{xorSource{RandomSource{c.rand}, f}}, sources...)
+	}
+	var cp *checkpointer
+	if c.checkpoint != "" {
+		if cp, err = loadCheckpoint(c.checkpoint, f, c, size, bufSize); err != nil {
+			return err
+		}
+		cc := *c
+		cc.progress = cp.advance(c.progress)
+		c = &cc
+	}
+	if c.invalidate {
+		written, err := invalidate(f, size, c)
+		stats.BytesOverwritten += written
+		if err != nil {
+			return &ShredError{Size: size, Written: written, Err: err}
+		}
+	}
+	var shared bufferSource
+	for pass, src := range sources {
+		var from []int64
+		if cp != nil {
+			if pass < cp.state.Pass {
+				stats.Passes++
+				continue
+			}
+			from = cp.state.Offsets
+		}
+		if src, err = passSource(src, pass); err != nil {
+			return &ShredError{Pass: pass, Size: size, Err: err}
+		}
+		src = c.random(src, pass)
+		if _, ok := src.(RandomSource); ok && c.sharedRand && size <= c.memLimit() {
+			if shared == nil {
+				shared = make(bufferSource, size)
+			}
+			if err := src.Fill(shared, 0); err != nil {
+				return &ShredError{Pass: pass, Size: size, Err: err}
+			}
+			src = shared
+		}
+		last := pass == len(sources)-1
+		var samples *sampler
+		if c.diagnostics && from == nil {
+			samples = newSampler(size)
+		}
+		hashed := last && c.paranoid && from == nil
+		bounds, hashes, written, err := shredPass(ctx, f, size, bufSize, src, pass, c, hashed, samples, from)
+		stats.BytesOverwritten += written
+		if cp != nil {
+			if cerr := cp.finish(pass, err == nil, len(sources)); err == nil {
+				err = cerr
+			}
+		}
+		if err != nil {
+			return err
+		}
+		if samples != nil {
+			if err := samples.check(f, pass); err != nil {
+				return err
+			}
+		}
+		if c.syncs(pass, len(sources)) {
+			if err := f.Sync(); err != nil {
+				return &ShredError{Pass: pass, Offset: size, Written: size, Size: size, Err: err}
+			}
+		}
+		stats.Passes++
+		if last && c.paranoid {
+			if err := f.Sync(); err != nil {
+				return err
+			}
+			err := verifyHashes(f, bounds, hashes)
+			if err != nil && deterministic(src) && c.transform == nil {
+				if verr := findMismatch(f, size, src, pass); verr != nil {
+					return verr
+				}
+			}
+			return err
+		}
+	}
+	return nil
+}
+
+// Overwrites the first and last invalidateSize bytes of f, or the whole
+// of it if it is smaller than both, with random data, and syncs them.
+// Returns the bytes written.
+func invalidate(f File, size int64, c *config) (int64, error) {
+	regions := [][2]int64{{0, size}}
+	if size > 2*invalidateSize {
+		regions = [][2]int64{{0, invalidateSize}, {size - invalidateSize, size}}
+	}
+	src := c.random(RandomSource{}, 0)
+	var written int64
+	for _, r := range regions {
+		b := make([]byte, r[1]-r[0])
+		if err := src.Fill(b, r[0]); err != nil {
+			return written, err
+		}
+		n, err := writePass(f, b, r[0], -1)
+		written += int64(n)
+		if err != nil {
+			return written, err
+		}
+	}
+	return written, f.Sync()
+}
+
+// Shreds the alternate data streams of the file at path, if any. On
+// NTFS they can hold data that would survive wiping the main stream.
+func shredStreams(ctx context.Context, path string, c *config) error {
+	streams, err := alternateStreams(path)
+	if err != nil {
+		return err
+	}
+	sc := *c
+	sc.checkpoint = "" // the checkpoint is for the file content only
+	sc.explicitSize = 0
+	for _, s := range streams {
+		f, err := os.OpenFile(path+s, os.O_RDWR, 0)
+		if err != nil {
+			return err
+		}
+		err = shredFile(ctx, f, &sc, &ShredStats{})
+		f.Close()
+		if err != nil {
 			return err
 		}
 	}
@@ -87,14 +582,453 @@ func shredFile(f *os.File) error {
 // Shreds file with given path string and removes it.
 // If it fails at any step of the process, the file could have
 // not been shreded correctly, it will not be removed.
-func Shred(path string) error {
-	f, err := os.OpenFile(path, os.O_RDWR, 644)
-	defer f.Close()
-	if err == nil {
-		err = shredFile(f)
-		if err == nil {
-			err = os.Remove(path)
+func Shred(path string, opts ...Option) error {
+	_, err := ShredWithStats(path, opts...)
+	return err
+}
+
+// Same as Shred, but also returns a summary of the work done.
+// Stats are filled as far as the process got, so on error Passes
+// holds the passes fully completed and BytesOverwritten every byte
+// written, including those of the pass that was interrupted.
+func ShredWithStats(path string, opts ...Option) (ShredStats, error) {
+	return shred(context.Background(), path, newConfig(opts))
+}
+
+// Same as Shred, but the file is only overwritten, and then synced:
+// it keeps its name, size and metadata, and is not removed. Its mode is
+// restored before returning if it changed meanwhile, even on error,
+// since e.g. writing to a setuid file clears its setuid bit.
+func Scrub(path string, opts ...Option) error {
+	c := newConfig(opts)
+	c.keep = true
+	_, err := shred(context.Background(), path, c)
+	return err
+}
+
+// Same as Scrub, but once overwritten the file is also truncated to
+// zero bytes, leaving an empty file with the same name, mode and owner,
+// e.g. to rotate a secret in place. WithSentinelMarker has no effect.
+func ShredAndTruncate(path string, opts ...Option) error {
+	c := newConfig(opts)
+	c.keep = true
+	c.truncate = true
+	_, err := shred(context.Background(), path, c)
+	return err
+}
+
+// Same as ShredWithStats, but stops as soon as possible once ctx is
+// done, returning ctx.Err(). Threads finish the batch they are writing,
+// so the returned stats tell exactly how much of the current pass got
+// overwritten, and the partially shreded file is not removed.
+func ShredContext(ctx context.Context, path string, opts ...Option) (ShredStats, error) {
+	return shred(ctx, path, newConfig(opts))
+}
+
+// Shreds and removes the file at path, or keeps it in place if the
+// config comes from Scrub or ShredAndTruncate. Safety checks run in
+// this order, each one before anything is written:
+//  1. the options are validated;
+//  2. ctx is checked;
+//  3. path is checked with Lstat to be a regular file, which refuses
+//     symlinks, directories, devices, pipes and sockets before opening
+//     them O_RDWR, which may have side effects on special files;
+//  4. the file is opened and, if requested, locked;
+//  5. the opened file is checked again to be a regular file, in case
+//     path was replaced between the Lstat and the open;
+//  6. the opened file is compared with the snapshot of ShredIfUnchanged,
+//     if any.
+func shred(ctx context.Context, path string, c *config) (stats ShredStats, err error) {
+	stats.Path = path
+	if err = c.validate(); err != nil {
+		return stats, err
+	}
+	if err = ctx.Err(); err != nil {
+		return stats, err
+	}
+	c, closeRandom, err := c.openRandom()
+	if err != nil {
+		return stats, err
+	}
+	defer closeRandom()
+	start := now()
+	defer func() {
+		stats.Duration = now().Sub(start)
+		if c.auditLog != "" {
+			if aerr := appendAudit(c.auditLog, stats, err); aerr != nil {
+				err = errors.Join(err, fmt.Errorf("audit log: %w", aerr))
+			}
+		}
+		if err != nil {
+			c.logf(LevelError, "%v", err)
+			return
+		}
+		c.logf(LevelInfo, "shreded %s: %d bytes in %d passes, %v", path, stats.BytesOverwritten, stats.Passes, stats.Duration)
+	}()
+	fsys := c.fsys()
+	info, err := fsys.Lstat(path)
+	if err != nil {
+		return stats, err
+	}
+	if !info.Mode().IsRegular() {
+		return stats, fmt.Errorf("%w: %s is %v", ErrNotRegularFile, path, info.Mode().Type())
+	}
+	if err = c.checkSelf(path, info); err != nil {
+		return stats, err
+	}
+	if err = c.checkAuditLog(path, info); err != nil {
+		return stats, err
+	}
+	f, err := fsys.OpenFile(path, os.O_RDWR, 644)
+	if err != nil {
+		return stats, err
+	}
+	// Closed early for WithRecreate and WithTrim, and only once.
+	closed := false
+	closeFile := func() error {
+		if closed {
+			return nil
 		}
+		closed = true
+		return f.Close()
+	}
+	defer closeFile()
+	if c.keep || c.decoy {
+		defer func() {
+			if rerr := restoreMode(f, info.Mode()); rerr != nil {
+				err = errors.Join(err, fmt.Errorf("could not restore the mode of %s: %w", path, rerr))
+			}
+		}()
+	}
+	osf, _ := f.(*os.File) // nil on other filesystems
+	if c.lock {
+		if err = lockFile(osf); err != nil {
+			return stats, err
+		}
+	}
+	stat, err := f.Stat()
+	if err != nil {
+		return stats, err
+	}
+	if !stat.Mode().IsRegular() || !c.sameFile(info, stat) {
+		return stats, fmt.Errorf("%w: %s changed before it was opened", ErrNotRegularFile, path)
+	}
+	if c.snapshot != nil {
+		if err = c.snapshot.check(path, stat); err != nil {
+			return stats, err
+		}
+	}
+	if stats.Size, err = c.extent(f, stat); err != nil {
+		return stats, err
+	}
+	if stats.Size != stat.Size() {
+		c.logf(LevelWarn, "%s reports %d bytes, overwriting the %d bytes given", path, stat.Size(), stats.Size)
+	}
+	if err = c.checkSize(path, stats.Size); err != nil {
+		return stats, err
+	}
+	if c.encAware {
+		if stats.Encrypted = encryptedVolume(stat); stats.Encrypted != "" && len(c.sources) > 1 {
+			c.logf(LevelInfo, "%s is on %s, overwriting it once", path, stats.Encrypted)
+			cc := *c
+			cc.sources = c.sources[len(c.sources)-1:]
+			c = &cc
+		}
+	}
+	stats.Threads = c.nthreads()
+	stats.Inode = fileID(path, stat).ino
+	if osf != nil {
+		stats.HardLinkCount = linkCount(osf, stat)
+		stats.Filesystem = filesystemType(osf)
+	}
+	if c.durableRemove {
+		if err = c.checkDurability(path, stats.Filesystem); err != nil {
+			return stats, err
+		}
+	}
+	if c.openCheck {
+		if stats.OpenByOthers = openByOthers(stat); stats.OpenByOthers > 0 {
+			c.logf(LevelWarn, "%s is open by %d other processes", path, stats.OpenByOthers)
+		}
+	}
+	if stats.HardLinkCount > 1 && c.refuseLinks && !c.force {
+		return stats, fmt.Errorf("%w: %s has %d links", ErrHardLinked, path, stats.HardLinkCount)
+	}
+	if c.confirm != nil && !c.confirm(path) {
+		return stats, fmt.Errorf("%w: %s", ErrNotConfirmed, path)
+	}
+	overwrite := shredFile
+	switch {
+	case c.noOverwrite:
+		overwrite = func(context.Context, File, *config, *ShredStats) error { return nil }
+	case c.blockHashes != "":
+		overwrite = shredChanged
+	}
+	if err = overwrite(ctx, f, c, &stats); err != nil {
+		eachShredError(err, func(e *ShredError) { e.Path = path })
+		return stats, c.removeOnError(path, c.vanished(path, err))
+	}
+	if osf != nil && !c.noOverwrite {
+		if err = shredStreams(ctx, path, c); err != nil {
+			return stats, c.removeOnError(path, c.vanished(path, err))
+		}
+	}
+	if c.xattrs {
+		if err = scrubXattrs(path); err != nil {
+			return stats, c.removeOnError(path, c.vanished(path, err))
+		}
+	}
+	if c.punchHoles {
+		if err = deallocate(osf, stats.Size); err != nil {
+			return stats, c.removeOnError(path, c.vanished(path, err))
+		}
+	}
+	if c.keep || c.decoy {
+		switch {
+		case c.truncate:
+			err = f.Truncate(0)
+		case c.sentinel:
+			err = writeSentinel(f, stats.Size, now())
+		}
+		if err != nil {
+			return stats, c.vanished(path, err)
+		}
+		if c.syncPolicy != SyncNever {
+			if err = f.Sync(); err != nil {
+				return stats, c.vanished(path, err)
+			}
+		}
+		if c.decoy {
+			if err = os.Chtimes(path, accessTime(info), info.ModTime()); err != nil {
+				return stats, c.vanished(path, err)
+			}
+		}
+		if c.blockHashes != "" {
+			if err = saveBlockHashes(c.blockHashes, f); err != nil {
+				return stats, err
+			}
+		}
+		return stats, nil
+	}
+	if c.preRemove != nil {
+		if err = c.preRemove(path); err != nil {
+			return stats, fmt.Errorf("%w: %s: %w", ErrRemovalVetoed, path, err)
+		}
+	}
+	if c.durableRemove {
+		if err = f.Sync(); err != nil {
+			return stats, c.vanished(path, err)
+		}
+		if err = syncDir(filepath.Dir(path)); err != nil {
+			return stats, err
+		}
+	}
+	if c.recreate && stats.Size <= inlineSize {
+		// and unlocked, path is about to lead to another file
+		if err = closeFile(); err != nil {
+			return stats, c.vanished(path, err)
+		}
+		if err = recreate(path, stats.Size); err != nil {
+			c.logf(LevelWarn, "could not recreate %s, removing it as it is: %v", path, err)
+		} else {
+			if rinfo, lerr := os.Lstat(path); lerr == nil {
+				stats.Inode = fileID(path, rinfo).ino
+			}
+			c.logf(LevelDebug, "recreated %s", path)
+		}
+	}
+	name := path
+	renames := c.renames
+	if c.noOverwrite && renames == 0 && c.finalName == "" {
+		renames = 1 // the name is all that is left to hide
+	}
+	if renames > 0 {
+		if name, err = obfuscateName(path, renames); err != nil {
+			return stats, c.vanished(name, err)
+		}
+	}
+	if c.finalName != "" {
+		if name, err = renameFinal(path, c.finalName); err != nil {
+			return stats, c.vanished(name, err)
+		}
+	}
+	if err = fsys.Remove(name); err != nil {
+		if err = c.vanished(name, err); errors.Is(err, ErrFileVanished) {
+			return stats, err
+		}
+		return stats, fmt.Errorf("%w: %w", ErrRemoveFailedAfterScrub, err)
+	}
+	if c.durableRemove {
+		if err = syncDir(filepath.Dir(path)); err != nil {
+			return stats, fmt.Errorf("removed %s, but not durably: %w", path, err)
+		}
+	}
+	if c.removalPolls > 0 {
+		if err = c.confirmRemoved(name); err != nil {
+			return stats, err
+		}
+	}
+	if c.trim {
+		// the blocks are only freed once the last descriptor is
+		if err = closeFile(); err != nil {
+			return stats, fmt.Errorf("removed %s, but could not close it: %w", path, err)
+		}
+		c.discard(filepath.Dir(path))
+	}
+	return stats, nil
+}
+
+// Discards the free blocks of the filesystem holding dir, logging how
+// many bytes were discarded, or why they could not be.
+func (c *config) discard(dir string) {
+	n, err := fstrim(dir)
+	if err != nil {
+		c.logf(LevelWarn, "could not discard the free blocks of the filesystem of %s: %v", dir, err)
+		return
+	}
+	c.logf(LevelInfo, "discarded %d free bytes of the filesystem of %s", n, dir)
+}
+
+// Sets back the permissions and setuid, setgid and sticky bits of f to
+// those of mode, if they changed, e.g. because writing to a setuid file
+// clears its setuid bit. Files that cannot change their mode are left
+// as they are.
+func restoreMode(f File, mode os.FileMode) error {
+	const bits = os.ModePerm | os.ModeSetuid | os.ModeSetgid | os.ModeSticky
+	ch, ok := f.(interface{ Chmod(os.FileMode) error })
+	if !ok {
+		return nil
+	}
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.Mode()&bits == mode&bits {
+		return nil
+	}
+	return ch.Chmod(mode & bits)
+}
+
+// With WithForceRemoveOnError, removes the file at path once err
+// stopped its shred, joining to err any failure removing it.
+func (c *config) removeOnError(path string, err error) error {
+	if !c.forceRemove || c.keep || errors.Is(err, ErrFileVanished) {
+		return err
+	}
+	if rerr := c.fsys().Remove(path); rerr != nil {
+		return errors.Join(err, rerr)
 	}
 	return err
 }
+
+// Shreds every given path, using at most const workers files at a time.
+// Returns the outcome of each path keyed by the path itself, and the
+// first error found, if any. Repeated paths are only shreded once.
+func ShredAll(paths []string, opts ...Option) (map[string]Result, error) {
+	specs := make([]PathSpec, len(paths))
+	for i, path := range paths {
+		specs[i].Path = path
+	}
+	return ShredAllSpecs(specs, opts...)
+}
+
+// A path to shred along with the options that apply only to it.
+type PathSpec struct {
+	Path string
+	Opts []Option
+}
+
+// Same as ShredAll, but each path can carry its own options, which are
+// applied after the given global ones and so override them. If a path
+// is repeated, only the options of its first spec are used.
+func ShredAllSpecs(specs []PathSpec, opts ...Option) (map[string]Result, error) {
+	return shredAll(context.Background(), specs, opts)
+}
+
+// Same as ShredAllSpecs, but once ctx is done no more paths are started,
+// and ctx.Err() is returned.
+func shredAll(ctx context.Context, specs []PathSpec, opts []Option) (map[string]Result, error) {
+	results := make(map[string]Result, len(specs))
+	paths := make([]string, len(specs))
+	for i, spec := range specs {
+		paths[i] = spec.Path
+	}
+	shredPaths(ctx, specs, opts, results)
+	if err := ctx.Err(); err != nil {
+		return results, err
+	}
+	return results, resultsErr(paths, results, newConfig(opts))
+}
+
+// Shreds the given paths with a pool of const workers goroutines,
+// storing the outcome of each one in results. Repeated paths are only
+// shreded once. Once ctx is done no more paths are dispatched, they are
+// reported as skipped instead, and the ones in flight abort between
+// batches with ctx.Err(). Past the global deadline of the options, if
+// any, or once their byte budget is spent, paths are skipped the same
+// way, but the ones in flight complete.
+func shredPaths(ctx context.Context, specs []PathSpec, opts []Option, results map[string]Result) {
+	gc := newConfig(opts)
+	deadline, budget := gc.deadline, gc.budget
+	var reduced sync.Map
+	seen := make(map[string]bool, len(specs))
+	var spent int64 // guarded by mu
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, workers)
+	for _, spec := range specs {
+		if seen[spec.Path] {
+			continue
+		}
+		seen[spec.Path] = true
+		acquired := false
+		select {
+		case sem <- struct{}{}:
+			acquired = true
+		case <-ctx.Done():
+		}
+		mu.Lock()
+		spentAll := budget > 0 && spent >= budget
+		mu.Unlock()
+		if ctx.Err() != nil || (!deadline.IsZero() && !now().Before(deadline)) || spentAll {
+			if acquired {
+				<-sem
+			}
+			mu.Lock()
+			results[spec.Path] = Result{Skipped: true}
+			mu.Unlock()
+			continue
+		}
+		c := newConfig(append(append([]Option{}, opts...), spec.Opts...))
+		c.reduced = &reduced
+		wg.Add(1)
+		go func(path string) {
+			defer wg.Done()
+			stats, err := shred(ctx, path, c)
+			r := Result{Stats: stats, Err: err}
+			if errors.Is(err, ErrNotConfirmed) {
+				r = Result{Stats: stats, Skipped: true}
+			}
+			mu.Lock()
+			results[path] = r
+			spent += stats.BytesOverwritten
+			mu.Unlock()
+			<-sem
+		}(spec.Path)
+	}
+	wg.Wait()
+}
+
+// Combines the errors of the given paths, in order, as the error mode
+// of the config says.
+func resultsErr(paths []string, results map[string]Result, c *config) error {
+	var errs []error
+	seen := make(map[string]bool, len(paths))
+	for _, path := range paths {
+		if err := results[path].Err; err != nil && !seen[path] {
+			errs = append(errs, err)
+		}
+		seen[path] = true
+	}
+	return c.combine(errs)
+}
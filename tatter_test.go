@@ -1,13 +1,22 @@
 package tatter
 
 import (
+	"bytes"
+	"context"
 	"crypto/rand"
+	"crypto/sha256"
 	"errors"
+	"fmt"
+	"hash"
 	"io"
 	"io/fs"
 	"os"
+	"runtime"
+	"sync"
+	"syscall"
 	"testing"
 	"testing/iotest"
+	"time"
 )
 
 type TestShredTable struct {
@@ -64,24 +73,32 @@ func patternIn(t *testing.T, pattern string, f *os.File) bool {
 	return false
 }
 
-func createNonWritable(t *testing.T, path string) (*os.File, error) {
+var errInjected = errors.New("injected fault")
+
+// Fails the writes of the given pass covering offset off, or all of its
+// writes if off is negative, with errInjected until the test ends.
+func failWrites(t *testing.T, pass int, off int64) {
 	t.Helper()
-	f, err := copyFile(t, "testdata/small.bin", path)
-	if err != nil {
-		return nil, err
-	}
-	f.Close()
-	if err := os.Chmod(path, 0400); err != nil {
-		return nil, err
+	injectFault = func(p int, o, n int64) error {
+		if p == pass && (off < 0 || o <= off && off < o+n) {
+			return errInjected
+		}
+		return nil
 	}
-	fro, err := os.OpenFile(path, os.O_RDONLY, 0400)
+	t.Cleanup(func() { injectFault = nil })
+}
+
+// Copies testdata/small.bin to path, removed when the test ends, and
+// makes every write of the first pass fail.
+func createFailing(t *testing.T, path string) (*os.File, error) {
+	t.Helper()
+	f, err := copyFile(t, "testdata/small.bin", path)
 	if err != nil {
 		return nil, err
 	}
-	if err = os.Remove(path); err != nil {
-		return nil, err
-	}
-	return fro, err
+	t.Cleanup(func() { os.Remove(path) })
+	failWrites(t, 0, -1)
+	return f, nil
 }
 
 func TestShred(t *testing.T) {
@@ -116,18 +133,569 @@ func TestShred(t *testing.T) {
 
 func TestShredFileWriteError(t *testing.T) {
 	testFile := "testdata/test/smallwrite.bin"
-	f, err := createNonWritable(t, testFile)
+	f, err := createFailing(t, testFile)
 	defer f.Close()
 	if err != nil {
-		t.Fatalf("non writable file not created")
+		t.Fatalf("failing file not created")
 	}
-	if err := shredFile(f); err == nil {
+	if err := shredFile(context.Background(), f, newConfig(nil), &ShredStats{}); err == nil {
 		t.Fatalf("expected write err, got nil\n")
 	}
 }
 
+func TestReadBack(t *testing.T) {
+	f, err := copyFile(t, "testdata/small.bin", "testdata/test/readback.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/readback.bin")
+	defer f.Close()
+	b, err := os.ReadFile("testdata/small.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	scratch := make([]byte, 4)
+	if err := readBack(f, b[2:6], scratch, 2, 0); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	found := b[4]
+	b[4]++
+	err = readBack(f, b[2:6], scratch, 2, 1)
+	want := &VerificationError{Pass: 1, Offset: 4, Expected: found + 1, Found: found}
+	var e *VerificationError
+	if !errors.Is(err, ErrVerificationFailed) || !errors.As(err, &e) || *e != *want {
+		t.Fatalf("expected %v, got %v\n", want, err)
+	}
+}
+
+// A file in memory whose writes corrupt the byte at a given offset.
+type corruptFile struct {
+	mu      sync.Mutex
+	b       []byte
+	corrupt int64
+}
+
+func (f *corruptFile) ReadAt(b []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return copy(b, f.b[off:]), nil
+}
+
+func (f *corruptFile) WriteAt(b []byte, off int64) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	n := copy(f.b[off:], b)
+	if f.corrupt >= off && f.corrupt < off+int64(n) {
+		f.b[f.corrupt] ^= 0x01
+	}
+	return n, nil
+}
+
+func (f *corruptFile) Name() string {
+	return "corrupt"
+}
+
+// Counts how many times every byte is written, and the size of every
+// write, in order.
+type countFile struct {
+	counts []int
+	writes []int64
+}
+
+func (f *countFile) ReadAt(b []byte, off int64) (int, error) {
+	return 0, io.EOF
+}
+
+func (f *countFile) WriteAt(b []byte, off int64) (int, error) {
+	for i := range b {
+		f.counts[off+int64(i)]++
+	}
+	f.writes = append(f.writes, int64(len(b)))
+	return len(b), nil
+}
+
+func (f *countFile) Name() string {
+	return "count"
+}
+
+func FuzzShredProc(f *testing.F) {
+	f.Add(uint16(0), uint16(0), uint16(1))
+	f.Add(uint16(0), uint16(4096), uint16(4096))
+	f.Add(uint16(7), uint16(4097), uint16(4096))
+	f.Add(uint16(1), uint16(4095), uint16(4096))
+	f.Add(uint16(3), uint16(1), uint16(1000))
+	f.Add(uint16(0), uint16(100), uint16(0))
+	f.Fuzz(func(t *testing.T, off, size, bufSize uint16) {
+		file := &countFile{counts: make([]int, int(off)+int(size)+1)}
+		res := make(chan procResult, 1)
+		shredProc(context.Background(), file, int64(off), int64(size), int64(bufSize), ConstantSource(0), 0, newConfig(nil), nil, res)
+		r := <-res
+		if bufSize == 0 {
+			if r.Err == nil || len(file.writes) != 0 {
+				t.Fatalf("expected err and no writes, got %v and %d writes\n", r.Err, len(file.writes))
+			}
+			return
+		}
+		if r.Err != nil || r.Offset != int64(off) || r.Bytes != int64(size) {
+			t.Fatalf("expected %d bytes at %d, got %d at %d, err: %v\n", size, off, r.Bytes, r.Offset, r.Err)
+		}
+		for i, n := range file.counts {
+			want := 0
+			if i >= int(off) && i < int(off)+int(size) {
+				want = 1
+			}
+			if n != want {
+				t.Fatalf("byte %d written %d times, want %d\n", i, n, want)
+			}
+		}
+		want := (int(size) + int(bufSize) - 1) / int(bufSize)
+		if len(file.writes) != want {
+			t.Fatalf("expected %d writes, got %d\n", want, len(file.writes))
+		}
+		for i, n := range file.writes {
+			last := int64(size) - int64(i)*int64(bufSize)
+			if n != int64(bufSize) && (i != len(file.writes)-1 || n != last) {
+				t.Fatalf("write %d of %d is %d bytes\n", i, len(file.writes), n)
+			}
+		}
+	})
+}
+
+type TestVerificationErrorTable struct {
+	name    string
+	src     PassSource
+	corrupt int64
+	want    VerificationError
+}
+
+func TestShredPassVerificationError(t *testing.T) {
+	var tests = []TestVerificationErrorTable{
+		{"Constant", ConstantSource(0xFF), 1234, VerificationError{Pass: 2, Offset: 1234, Expected: 0xFF, Found: 0xFE}},
+		{"Pattern", PatternSource{0x10, 0x20}, 4097, VerificationError{Pass: 2, Offset: 4097, Expected: 0x20, Found: 0x21}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := &corruptFile{b: make([]byte, 10000), corrupt: tt.corrupt}
+			c := newConfig([]Option{WithReadAfterWrite(true)})
+			_, _, _, err := shredPass(context.Background(), f, 10000, 1000, tt.src, 2, c, false, nil, nil)
+			var e *VerificationError
+			if !errors.As(err, &e) || *e != tt.want {
+				t.Fatalf("expected %v, got %v\n", &tt.want, err)
+			}
+			// The paranoid verification at the end of the pass finds
+			// the same byte.
+			err = findMismatch(f, 10000, tt.src, 2)
+			if !errors.As(err, &e) || *e != tt.want {
+				t.Fatalf("expected %v, got %v\n", &tt.want, err)
+			}
+		})
+	}
+}
+
+func BenchmarkReadAfterWrite(b *testing.B) {
+	for _, enabled := range []bool{false, true} {
+		b.Run(fmt.Sprintf("%v", enabled), func(b *testing.B) {
+			f, err := os.Create("testdata/test/bench.bin")
+			if err != nil {
+				b.Fatalf("err: %v\n", err)
+			}
+			defer os.Remove("testdata/test/bench.bin")
+			defer f.Close()
+			if err := f.Truncate(1 << 24); err != nil {
+				b.Fatalf("err: %v\n", err)
+			}
+			c := newConfig([]Option{WithStandard(StandardQuick), WithReadAfterWrite(enabled)})
+			b.SetBytes(1 << 24)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := shredFile(context.Background(), f, c, &ShredStats{}); err != nil {
+					b.Fatalf("err: %v\n", err)
+				}
+			}
+		})
+	}
+}
+
+// Discards every write.
+type discardFile struct{}
+
+func (discardFile) ReadAt(b []byte, off int64) (int, error) {
+	return 0, io.EOF
+}
+
+func (discardFile) WriteAt(b []byte, off int64) (int, error) {
+	return len(b), nil
+}
+
+func (discardFile) Name() string {
+	return "discard"
+}
+
+// Compares the CPU spent filling batches of a constant pass, which fills
+// a template once, with an equivalent offset source, filled batch by
+// batch.
+func BenchmarkTemplate(b *testing.B) {
+	srcs := []PassSource{ConstantSource(0x55), PatternSource{0x92, 0x49, 0x24}, OffsetSource(func(off int64) byte { return 0x55 })}
+	for _, src := range srcs {
+		b.Run(fmt.Sprintf("%T", src), func(b *testing.B) {
+			res := make(chan procResult, 1)
+			c := newConfig(nil)
+			b.SetBytes(1 << 24)
+			for i := 0; i < b.N; i++ {
+				shredProc(context.Background(), discardFile{}, 0, 1<<24, 1<<20, src, 0, c, nil, res)
+				if r := <-res; r.Err != nil {
+					b.Fatalf("err: %v\n", r.Err)
+				}
+			}
+		})
+	}
+}
+
+func TestScrub(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/scrub.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/scrub.bin")
+	if err := Scrub("testdata/test/scrub.bin", WithStandard(StandardQuick)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	b, err := os.ReadFile("testdata/test/scrub.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if !bytes.Equal(b, make([]byte, 40716)) {
+		t.Fatalf("expected the file zeroed and kept\n")
+	}
+}
+
+func TestScrubRestoresMode(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/scrubmode.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/scrubmode.bin")
+	mode := 0640 | os.ModeSetgid
+	if err := os.Chmod("testdata/test/scrubmode.bin", mode); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	var once sync.Once
+	chmod := func(buf []byte, pass int) {
+		once.Do(func() {
+			if err := os.Chmod("testdata/test/scrubmode.bin", 0600); err != nil {
+				t.Errorf("err: %v\n", err)
+			}
+		})
+	}
+	if err := Scrub("testdata/test/scrubmode.bin", WithBufferTransform(chmod)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	info, err := os.Stat("testdata/test/scrubmode.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if info.Mode() != mode {
+		t.Fatalf("expected mode %v restored, got %v\n", mode, info.Mode())
+	}
+}
+
+func TestShredAndTruncate(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/truncate.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/truncate.bin")
+	if err := os.Chmod("testdata/test/truncate.bin", 0o600); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	before, err := os.Stat("testdata/test/truncate.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := ShredAndTruncate("testdata/test/truncate.bin", WithSentinelMarker(true)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	after, err := os.Stat("testdata/test/truncate.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if after.Size() != 0 || after.Mode() != before.Mode() || !os.SameFile(before, after) {
+		t.Fatalf("expected the same empty file with mode %v, got size %d and mode %v\n", before.Mode(), after.Size(), after.Mode())
+	}
+}
+
+type TestForceRemoveTable struct {
+	name    string
+	enabled bool
+	removed bool
+}
+
+func TestWithForceRemoveOnError(t *testing.T) {
+	var tests = []TestForceRemoveTable{
+		{"Default", false, false},
+		{"Enabled", true, true},
+	}
+	failing := RandomSource{iotest.ErrReader(errors.New("Rand err"))}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := copyFile(t, "testdata/large.bin", "testdata/test/forceremove.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			f.Close()
+			defer os.Remove("testdata/test/forceremove.bin")
+			err = Shred("testdata/test/forceremove.bin", WithPassSources(failing), WithForceRemoveOnError(tt.enabled))
+			var e *ShredError
+			if !errors.As(err, &e) {
+				t.Fatalf("expected a *ShredError, got %v\n", err)
+			}
+			_, err = os.Stat("testdata/test/forceremove.bin")
+			if removed := errors.Is(err, fs.ErrNotExist); removed != tt.removed {
+				t.Fatalf("expected removed %v, got %v\n", tt.removed, removed)
+			}
+		})
+	}
+}
+
+// Replaces the clock with one stopped at the returned time, which the
+// test can change, until the test ends.
+func stopClock(t *testing.T, at time.Time) *time.Time {
+	var mu sync.Mutex
+	now = func() time.Time {
+		mu.Lock()
+		defer mu.Unlock()
+		return at
+	}
+	t.Cleanup(func() { now = time.Now })
+	return &at
+}
+
+func TestShredStatsDurationClock(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/clock.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	var step time.Duration
+	now = func() time.Time {
+		step += time.Second
+		return start.Add(step)
+	}
+	defer func() { now = time.Now }()
+	stats, err := ShredWithStats("testdata/test/clock.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if stats.Duration != time.Second {
+		t.Fatalf("expected a duration of 1s, got %v\n", stats.Duration)
+	}
+}
+
+func TestShredAllGlobalDeadlineClock(t *testing.T) {
+	f, err := copyFile(t, "testdata/small.bin", "testdata/test/deadline.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/deadline.bin")
+	deadline := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	clock := stopClock(t, deadline.Add(-time.Second))
+	*clock = deadline
+	results, err := ShredAll([]string{"testdata/test/deadline.bin"}, WithGlobalDeadline(deadline))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	for path, r := range results {
+		if !r.Skipped {
+			t.Fatalf("expected %s skipped at the deadline\n", path)
+		}
+	}
+}
+
+func TestWithByteBudget(t *testing.T) {
+	var paths []string
+	for i := 0; i < 10; i++ {
+		path := fmt.Sprintf("testdata/test/budget%d.bin", i)
+		f, err := copyFile(t, "testdata/large.bin", path)
+		if err != nil {
+			t.Fatalf("err: %v\n", err)
+		}
+		f.Close()
+		defer os.Remove(path)
+		paths = append(paths, path)
+	}
+	results, err := ShredAll(paths, WithByteBudget(1))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	var shreded, skipped int
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			skipped++
+		case r.Stats.BytesOverwritten == 3*3150:
+			shreded++
+		}
+	}
+	// The budget is spent by the first file, but up to const workers
+	// files were already in flight.
+	if shreded < 1 || shreded > workers || shreded+skipped != 10 {
+		t.Fatalf("expected 1 to %d files shreded and the rest skipped, got %d and %d\n", workers, shreded, skipped)
+	}
+	if err := newConfig([]Option{WithByteBudget(-1)}).validate(); err == nil {
+		t.Fatalf("expected negative budget err, got nil\n")
+	}
+}
+
+func TestWithConfirm(t *testing.T) {
+	paths := []string{"testdata/test/confirm1.bin", "testdata/test/confirm2.bin", "testdata/test/confirm3.bin", "testdata/test/confirm4.bin", "testdata/test/confirm5.bin"}
+	for _, path := range paths {
+		f, err := copyFile(t, "testdata/large.bin", path)
+		if err != nil {
+			t.Fatalf("err: %v\n", err)
+		}
+		f.Close()
+		defer os.Remove(path)
+	}
+	var mu sync.Mutex
+	asked := make(map[string]int)
+	inside := 0
+	confirm := func(path string) bool {
+		mu.Lock()
+		inside++
+		asked[path]++
+		concurrent := inside > 1
+		mu.Unlock()
+		if concurrent {
+			t.Errorf("confirmations ran concurrently\n")
+		}
+		time.Sleep(time.Millisecond)
+		mu.Lock()
+		inside--
+		mu.Unlock()
+		return path != paths[1]
+	}
+	results, err := ShredAll(paths, WithConfirm(confirm))
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	for _, path := range paths {
+		if asked[path] != 1 {
+			t.Fatalf("expected %s confirmed once, got %d\n", path, asked[path])
+		}
+		if skipped := path == paths[1]; results[path].Skipped != skipped {
+			t.Fatalf("expected %s skipped %v, got %+v\n", path, skipped, results[path])
+		}
+	}
+	b, err := os.ReadFile(paths[1])
+	if err != nil {
+		t.Fatalf("expected the declined file kept, got %v\n", err)
+	}
+	orig, _ := os.ReadFile("testdata/large.bin")
+	if !bytes.Equal(b, orig) {
+		t.Fatalf("expected the declined file untouched\n")
+	}
+	if err := Shred(paths[1], WithConfirm(confirm)); !errors.Is(err, ErrNotConfirmed) {
+		t.Fatalf("expected ErrNotConfirmed, got %v\n", err)
+	}
+}
+
+func TestWithSequentialSingleThread(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/sequential.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/sequential.bin")
+	defer f.Close()
+	var offsets []int64
+	c := newConfig([]Option{WithSequentialSingleThread(true), WithWriteCount(10), WithStandard(StandardQuick)})
+	c.progress = func(pass int, off, n int64) {
+		offsets = append(offsets, off)
+	}
+	if err := shredFile(context.Background(), f, c, &ShredStats{}); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if len(offsets) != 10 {
+		t.Fatalf("expected 10 writes, got %d\n", len(offsets))
+	}
+	for i := 1; i < len(offsets); i++ {
+		if offsets[i] <= offsets[i-1] {
+			t.Fatalf("expected ascending writes, got %v\n", offsets)
+		}
+	}
+}
+
+// Compares the default threads with a single sequential one. On a
+// spinning disk the single thread is expected to win, while SSDs and
+// the page cache favour the default.
+func BenchmarkSequential(b *testing.B) {
+	for _, sequential := range []bool{false, true} {
+		b.Run(fmt.Sprintf("%v", sequential), func(b *testing.B) {
+			f, err := os.Create("testdata/test/bench.bin")
+			if err != nil {
+				b.Fatalf("err: %v\n", err)
+			}
+			defer os.Remove("testdata/test/bench.bin")
+			defer f.Close()
+			if err := f.Truncate(1 << 24); err != nil {
+				b.Fatalf("err: %v\n", err)
+			}
+			c := newConfig([]Option{WithPasses(1), WithSequentialSingleThread(sequential), WithParanoidVerify(true)})
+			b.SetBytes(1 << 24)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := shredFile(context.Background(), f, c, &ShredStats{}); err != nil {
+					b.Fatalf("err: %v\n", err)
+				}
+			}
+		})
+	}
+}
+
+func TestWithDurableRemove(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/durable.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	if err := Shred("testdata/test/durable.bin", WithDurableRemove(true), WithRename(true)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := VerifyRemoved("testdata/test/durable.bin"); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+}
+
+func TestShredSelf(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/self.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/self.bin")
+	if err := os.Symlink("self.bin", "testdata/test/selflink"); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/selflink")
+	executable = func() (string, error) { return "testdata/test/selflink", nil }
+	defer func() { executable = os.Executable }()
+	if err := Shred("testdata/test/self.bin"); !errors.Is(err, ErrSelfShred) {
+		t.Fatalf("expected ErrSelfShred, got %v\n", err)
+	}
+	if _, err := DryRun("testdata/test/self.bin"); !errors.Is(err, ErrSelfShred) {
+		t.Fatalf("expected ErrSelfShred, got %v\n", err)
+	}
+	if err := Shred("testdata/test/self.bin", WithForce(true)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+}
+
 func TestShredFileNon(t *testing.T) {
-	if err := shredFile(nil); err == nil {
+	if err := shredFile(context.Background(), nil, newConfig(nil), &ShredStats{}); err == nil {
 		t.Fatalf("expected *PathError err, got nil\n")
 	}
 }
@@ -150,37 +718,578 @@ func TestCalcBuf(t *testing.T) {
 }
 
 func TestShredProcRandError(t *testing.T) {
-	errs := make(chan error)
+	res := make(chan procResult)
 	testFile := "testdata/test/smallwrite.bin"
-	f, err := createNonWritable(t, testFile)
+	f, err := createFailing(t, testFile)
 	defer f.Close()
 	if err != nil {
-		t.Fatalf("non writable file not created")
+		t.Fatalf("failing file not created")
 	}
-	go shredProc(f, 10, 10, iotest.ErrReader(errors.New("Rand err")), errs)
-	if err := <-errs; err == nil {
+	go shredProc(context.Background(), f, 0, 10, 10, RandomSource{iotest.ErrReader(errors.New("Rand err"))}, 0, newConfig(nil), nil, res)
+	if r := <-res; r.Err == nil {
 		t.Fatalf("expected rand err, got nil\n")
 	}
 }
 
 func TestShredProcBuffer(t *testing.T) {
-	errs := make(chan error)
+	res := make(chan procResult)
 	testFile := "testdata/test/smallwrite.bin"
-	f, err := createNonWritable(t, testFile)
+	f, err := createFailing(t, testFile)
 	defer f.Close()
 	if err != nil {
-		t.Fatalf("Non writable file not created")
+		t.Fatalf("failing file not created")
 	}
-	go shredProc(f, 100, -1000, rand.Reader, errs)
-	if err := <-errs; err == nil {
+	go shredProc(context.Background(), f, 0, 100, -1000, RandomSource{rand.Reader}, 0, newConfig(nil), nil, res)
+	if r := <-res; r.Err == nil {
 		t.Fatalf("expected buff err, got nil\n")
 	}
 }
 
+func TestShredProcSharedBuffer(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/sharedproc.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/sharedproc.bin")
+	defer f.Close()
+	shared := make(bufferSource, 40716)
+	rand.Read(shared)
+	res := make(chan procResult)
+	go shredProc(context.Background(), f, 10000, 30716, 3000, shared, 0, newConfig(nil), nil, res)
+	if r := <-res; r.Err != nil || r.Bytes != 30716 {
+		t.Fatalf("got %d bytes written: %v\n", r.Bytes, r.Err)
+	}
+	b := make([]byte, 30716)
+	if _, err := f.ReadAt(b, 10000); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if !bytes.Equal(b, shared[10000:]) {
+		t.Fatalf("expected the shared buffer written at its offsets\n")
+	}
+}
+
 func TestShredProcNilError(t *testing.T) {
-	errs := make(chan error)
-	go shredProc(nil, 10, 10, iotest.ErrReader(errors.New("Rand err")), errs)
-	if err := <-errs; err == nil {
+	res := make(chan procResult)
+	go shredProc(context.Background(), nil, 0, 10, 10, RandomSource{iotest.ErrReader(errors.New("Rand err"))}, 0, newConfig(nil), nil, res)
+	if r := <-res; r.Err == nil {
 		t.Fatalf("expected file err, got nil\n")
 	}
 }
+
+func TestShredAll(t *testing.T) {
+	paths := []string{"testdata/test/all1.bin", "testdata/test/all2.bin", "testdata/test/all1.bin", "testdata/test/nonexistent"}
+	for _, path := range paths[:2] {
+		f, err := copyFile(t, "testdata/small.bin", path)
+		if err != nil {
+			t.Fatalf("err: %v\n", err)
+		}
+		f.Close()
+	}
+	results, err := ShredAll(paths)
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("got: %v, want %v\n", err, fs.ErrNotExist)
+	}
+	if len(results) != 3 {
+		t.Fatalf("got %d results, want 3\n", len(results))
+	}
+	for _, path := range paths[:2] {
+		r := results[path]
+		if r.Err != nil || r.Stats.Size != 8 || r.Stats.BytesOverwritten != 8*3 || r.Stats.Passes != 3 {
+			t.Fatalf("unexpected result for %s: %+v\n", path, r)
+		}
+	}
+}
+
+func TestShredFileVanished(t *testing.T) {
+	path := "testdata/test/vanish.bin"
+	f, err := copyFile(t, "testdata/extra.bin", path)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	var once sync.Once
+	slow := WithBufferTransform(func(buf []byte, pass int) {
+		once.Do(func() {
+			if err := os.Remove(path); err != nil {
+				t.Errorf("err: %v\n", err)
+			}
+		})
+		time.Sleep(time.Millisecond)
+	})
+	stats, err := ShredWithStats(path, slow)
+	if !errors.Is(err, ErrFileVanished) {
+		t.Fatalf("got: %v, want %v\n", err, ErrFileVanished)
+	}
+	if stats.Duration <= 0 {
+		t.Fatalf("duration not recorded\n")
+	}
+}
+
+type TestPartitionTable struct {
+	name          string
+	size, bufSize int64
+	want          []int64
+}
+
+func TestPartition(t *testing.T) {
+	var tests = []TestPartitionTable{
+		{"Empty", 0, 4096, []int64{0, 0}},
+		{"SingleBuf", 100, 4096, []int64{0, 100}},
+		{"TwoBufs", 5000, 4096, []int64{0, 4096, 5000}},
+		{"Even", 30, 10, []int64{0, 10, 20, 30}},
+		{"Remainder", 95, 10, []int64{0, 40, 80, 95}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := partition(tt.size, tt.bufSize, threads)
+			if len(got) != len(tt.want) {
+				t.Fatalf("expected %v, got %v\n", tt.want, got)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("expected %v, got %v\n", tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+func TestShredParanoidVerify(t *testing.T) {
+	for _, file := range []string{"small.bin", "extra.bin", "empty.bin"} {
+		t.Run(file, func(t *testing.T) {
+			f, err := copyFile(t, "testdata/"+file, "testdata/test/"+file)
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			f.Close()
+			if err := Shred("testdata/test/"+file, WithParanoidVerify(true)); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+		})
+	}
+}
+
+func TestVerifyHashesMismatch(t *testing.T) {
+	f, err := os.Open("testdata/small.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer f.Close()
+	h := sha256.New()
+	h.Write([]byte("Small124"))
+	if err := verifyHashes(f, []int64{0, 8}, []hash.Hash{h}); !errors.Is(err, ErrVerificationFailed) {
+		t.Fatalf("got: %v, want %v\n", err, ErrVerificationFailed)
+	}
+	h.Reset()
+	h.Write([]byte("Small123"))
+	if err := verifyHashes(f, []int64{0, 8}, []hash.Hash{h}); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+}
+
+func TestShredContextCancel(t *testing.T) {
+	path := "testdata/test/cancel.bin"
+	f, err := copyFile(t, "testdata/extra.bin", path)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove(path)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cancelOnSecondPass := WithBufferTransform(func(buf []byte, pass int) {
+		if pass == 1 {
+			cancel()
+		}
+	})
+	stats, err := ShredContext(ctx, path, cancelOnSecondPass)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got: %v, want %v\n", err, context.Canceled)
+	}
+	if stats.Passes != 1 {
+		t.Fatalf("got %d completed passes, want 1\n", stats.Passes)
+	}
+	if stats.BytesOverwritten <= stats.Size || stats.BytesOverwritten >= 2*stats.Size {
+		t.Fatalf("got %d bytes overwritten, want a partial second pass of %d bytes\n", stats.BytesOverwritten, stats.Size)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("cancelled file has been removed: %v\n", err)
+	}
+	if _, err := ShredContext(ctx, path); !errors.Is(err, context.Canceled) {
+		t.Fatalf("got: %v, want %v\n", err, context.Canceled)
+	}
+}
+
+func TestShredAllSpecs(t *testing.T) {
+	specs := []PathSpec{
+		{Path: "testdata/test/spec1.bin"},
+		{Path: "testdata/test/spec2.bin", Opts: []Option{WithStandard(StandardGutmann)}},
+		{Path: "testdata/test/spec3.bin", Opts: []Option{WithStandard(Standard(42))}},
+	}
+	for _, spec := range specs {
+		f, err := copyFile(t, "testdata/small.bin", spec.Path)
+		if err != nil {
+			t.Fatalf("err: %v\n", err)
+		}
+		f.Close()
+	}
+	defer os.Remove("testdata/test/spec3.bin")
+	results, err := ShredAllSpecs(specs, WithStandard(StandardQuick))
+	if err == nil {
+		t.Fatalf("expected unknown standard err, got nil\n")
+	}
+	if p := results["testdata/test/spec1.bin"].Stats.Passes; p != 1 {
+		t.Fatalf("got %d passes with global options, want 1\n", p)
+	}
+	if p := results["testdata/test/spec2.bin"].Stats.Passes; p != 35 {
+		t.Fatalf("got %d passes with overridden options, want 35\n", p)
+	}
+	if results["testdata/test/spec3.bin"].Err == nil {
+		t.Fatalf("expected unknown standard err for spec3.bin\n")
+	}
+}
+
+func TestShredProcResult(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/procres.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/procres.bin")
+	defer f.Close()
+	res := make(chan procResult)
+	go shredProc(context.Background(), f, 100, 25, 10, RandomSource{}, 0, newConfig(nil), nil, res)
+	if r := <-res; r.Err != nil || r.Offset != 100 || r.Bytes != 25 {
+		t.Fatalf("unexpected result %+v\n", r)
+	}
+	failing := RandomSource{io.MultiReader(io.LimitReader(rand.Reader, 20), iotest.ErrReader(errors.New("Rand err")))}
+	go shredProc(context.Background(), f, 100, 25, 10, failing, 0, newConfig(nil), nil, res)
+	if r := <-res; r.Err == nil || r.Offset != 100 || r.Bytes != 20 {
+		t.Fatalf("unexpected result %+v\n", r)
+	}
+}
+
+func TestShredRemoveFailedAfterScrub(t *testing.T) {
+	path := "testdata/test/undeletable.bin"
+	f, err := copyFile(t, "testdata/small.bin", path)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove(path)
+	defer f.Close()
+	remove = func(string) error { return &fs.PathError{Op: "remove", Path: path, Err: syscall.EPERM} }
+	defer func() { remove = os.Remove }()
+	err = Shred(path)
+	if !errors.Is(err, ErrRemoveFailedAfterScrub) || !errors.Is(err, syscall.EPERM) {
+		t.Fatalf("got: %v, want %v\n", err, ErrRemoveFailedAfterScrub)
+	}
+	if patternIn(t, "Small123", f) {
+		t.Fatalf("pattern found in %s\n", path)
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := shredFile(context.Background(), nil, newConfig(nil), &ShredStats{}); errors.Is(err, ErrRemoveFailedAfterScrub) {
+		t.Fatalf("overwrite failure reported as a remove failure\n")
+	}
+}
+
+func TestShredNotRegularFile(t *testing.T) {
+	if err := os.Mkdir("testdata/test/adir", 0755); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/adir")
+	if err := Shred("testdata/test/adir"); !errors.Is(err, ErrNotRegularFile) {
+		t.Fatalf("got: %v, want %v\n", err, ErrNotRegularFile)
+	}
+	link := "testdata/test/alink"
+	if err := os.Symlink("../small.bin", link); err != nil {
+		t.Skipf("symlinks not supported: %v\n", err)
+	}
+	defer os.Remove(link)
+	if err := Shred(link); !errors.Is(err, ErrNotRegularFile) {
+		t.Fatalf("got: %v, want %v\n", err, ErrNotRegularFile)
+	}
+	if b, err := os.ReadFile("testdata/small.bin"); err != nil || string(b) != "Small123" {
+		t.Fatalf("symlink target has been modified\n")
+	}
+}
+
+func TestShredHardLinks(t *testing.T) {
+	path, link := "testdata/test/linked.bin", "testdata/test/linked2.bin"
+	f, err := copyFile(t, "testdata/small.bin", path)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer f.Close()
+	if err := os.Link(path, link); err != nil {
+		os.Remove(path)
+		t.Skipf("hard links not supported: %v\n", err)
+	}
+	defer os.Remove(link)
+	if err := Shred(path, WithRefuseHardLinks(true)); !errors.Is(err, ErrHardLinked) {
+		t.Fatalf("got: %v, want %v\n", err, ErrHardLinked)
+	}
+	if !patternIn(t, "Small123", f) {
+		t.Fatalf("refused file has been overwritten\n")
+	}
+	stats, err := ShredWithStats(path)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if stats.HardLinkCount != 2 {
+		t.Fatalf("got %d links, want 2\n", stats.HardLinkCount)
+	}
+	if b, err := os.ReadFile(link); err != nil || string(b) == "Small123" {
+		t.Fatalf("content not overwritten through the other link: %v\n", err)
+	}
+	stats, err = ShredWithStats(link)
+	if err != nil || stats.HardLinkCount != 1 {
+		t.Fatalf("got %d links, %v, want 1\n", stats.HardLinkCount, err)
+	}
+}
+
+func TestWithDecoy(t *testing.T) {
+	f, err := copyFile(t, "testdata/large.bin", "testdata/test/decoy.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/decoy.bin")
+	atime := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	mtime := time.Date(2021, 1, 2, 3, 4, 5, 0, time.UTC)
+	if err := os.Chtimes("testdata/test/decoy.bin", atime, mtime); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := Shred("testdata/test/decoy.bin", WithDecoy(true)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	info, err := os.Stat("testdata/test/decoy.bin")
+	if err != nil {
+		t.Fatalf("expected the decoy left in place, got %v\n", err)
+	}
+	if info.Size() != 3150 || !info.ModTime().Equal(mtime) || !accessTime(info).Equal(atime) {
+		t.Fatalf("expected 3150 bytes at %v and %v, got %d at %v and %v\n", atime, mtime, info.Size(), accessTime(info), info.ModTime())
+	}
+	want, err := os.ReadFile("testdata/large.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if got, err := os.ReadFile("testdata/test/decoy.bin"); err != nil || bytes.Equal(got, want) {
+		t.Fatalf("expected the content overwritten, err: %v\n", err)
+	}
+}
+
+func TestExtentProcfs(t *testing.T) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		t.Skipf("no procfs: %v\n", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if _, err := newConfig(nil).extent(f, info); !errors.Is(err, ErrUnknownSize) {
+		t.Fatalf("expected ErrUnknownSize, got %v\n", err)
+	}
+	if size, err := newConfig([]Option{WithExplicitSize(100)}).extent(f, info); err != nil || size != 100 {
+		t.Fatalf("expected 100 bytes, got %d, err: %v\n", size, err)
+	}
+}
+
+func TestWithExplicitSize(t *testing.T) {
+	f, err := copyFile(t, "testdata/empty.bin", "testdata/test/explicit.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/explicit.bin")
+	if p, err := DryRun("testdata/test/explicit.bin", WithExplicitSize(100)); err != nil || p.Size != 100 {
+		t.Fatalf("expected a plan for 100 bytes, got %+v, err: %v\n", p, err)
+	}
+	if err := Scrub("testdata/test/explicit.bin", WithExplicitSize(100)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	info, err := os.Stat("testdata/test/explicit.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if info.Size() != 100 {
+		t.Fatalf("expected 100 bytes written, got %d\n", info.Size())
+	}
+	// Files reporting their size are not affected.
+	if err := Scrub("testdata/test/explicit.bin", WithExplicitSize(10)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if info, err := os.Stat("testdata/test/explicit.bin"); err != nil || info.Size() != 100 {
+		t.Fatalf("expected 100 bytes kept, got %v\n", err)
+	}
+	if err := newConfig([]Option{WithExplicitSize(-1)}).validate(); err == nil {
+		t.Fatalf("expected negative size err, got nil\n")
+	}
+}
+
+func TestWithQuickInvalidateFirst(t *testing.T) {
+	const size = 300 << 10
+	orig := bytes.Repeat([]byte{0xA5}, size)
+	if err := os.WriteFile("testdata/test/invalidate.bin", orig, 0644); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/invalidate.bin")
+	// Cancels the shred right before it starts overwriting, so only the
+	// invalidated regions get written.
+	ctx, cancel := context.WithCancel(context.Background())
+	confirm := func(path string) bool {
+		cancel()
+		return true
+	}
+	stats, err := ShredContext(ctx, "testdata/test/invalidate.bin", WithQuickInvalidateFirst(true), WithConfirm(confirm))
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v\n", err)
+	}
+	if stats.BytesOverwritten != 2*invalidateSize || stats.Passes != 0 {
+		t.Fatalf("expected %d bytes and no pass, got %d and %d\n", 2*invalidateSize, stats.BytesOverwritten, stats.Passes)
+	}
+	b, err := os.ReadFile("testdata/test/invalidate.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	head, middle, tail := b[:invalidateSize], b[invalidateSize:size-invalidateSize], b[size-invalidateSize:]
+	if bytes.Equal(head, orig[:invalidateSize]) || bytes.Equal(tail, orig[:invalidateSize]) {
+		t.Fatalf("expected the head and tail invalidated\n")
+	}
+	if !bytes.Equal(middle, orig[invalidateSize:size-invalidateSize]) {
+		t.Fatalf("expected the middle untouched\n")
+	}
+}
+
+func TestInvalidateFault(t *testing.T) {
+	f, err := copyFile(t, "testdata/extra.bin", "testdata/test/invalidatefault.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	defer os.Remove("testdata/test/invalidatefault.bin")
+	defer f.Close()
+	failWrites(t, -1, 0)
+	written, err := invalidate(f, 40716, newConfig(nil))
+	if !errors.Is(err, errInjected) || written != 0 {
+		t.Fatalf("expected the first write failed, got %d bytes: %v\n", written, err)
+	}
+}
+
+func TestShredInode(t *testing.T) {
+	f, err := copyFile(t, "testdata/small.bin", "testdata/test/inode.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	info, err := f.Stat()
+	f.Close()
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	stats, err := ShredWithStats("testdata/test/inode.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	want := fileID("testdata/test/inode.bin", info).ino
+	if stats.Inode != want || (runtime.GOOS != "windows" && want == 0) {
+		t.Fatalf("expected inode %d, got %d\n", want, stats.Inode)
+	}
+}
+
+type TestWithPreRemoveHookTable struct {
+	name string
+	veto error
+}
+
+func TestWithPreRemoveHook(t *testing.T) {
+	var tests = []TestWithPreRemoveHookTable{
+		{"Allowed", nil},
+		{"Vetoed", errors.New("not all zeros")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := copyFile(t, "testdata/small.bin", "testdata/test/preremove.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			f.Close()
+			defer os.Remove("testdata/test/preremove.bin")
+			calls := 0
+			hook := func(path string) error {
+				calls++
+				b, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				if !bytes.Equal(b, make([]byte, len(b))) {
+					t.Errorf("expected %s overwritten with zeros, got %x\n", path, b)
+				}
+				return tt.veto
+			}
+			err = Shred("testdata/test/preremove.bin", WithPassSources(ConstantSource(0)), WithPreRemoveHook(hook))
+			if calls != 1 {
+				t.Fatalf("expected the hook called once, got %d\n", calls)
+			}
+			_, serr := os.Stat("testdata/test/preremove.bin")
+			if tt.veto == nil && (err != nil || serr == nil) {
+				t.Fatalf("expected the file removed, got %v\n", err)
+			}
+			if tt.veto != nil && (!errors.Is(err, ErrRemovalVetoed) || !errors.Is(err, tt.veto) || serr != nil) {
+				t.Fatalf("expected the removal vetoed, got %v and %v\n", err, serr)
+			}
+		})
+	}
+}
+
+type TestInjectedFaultTable struct {
+	name    string
+	pass    int
+	off     int64
+	offset  int64 // of the failing write
+	written int64 // by all threads of the pass
+}
+
+func TestInjectedFault(t *testing.T) {
+	// extra.bin is 40716 bytes, written 3393 at a time by 3 threads
+	// with partitions of 13572 bytes.
+	var tests = []TestInjectedFaultTable{
+		{"FirstWrite", 0, 0, 0, 2 * 13572},
+		{"SecondThread", 1, 20000, 16965, 13572 + 3393 + 13572},
+		{"LastWrite", 2, 40715, 37323, 40716 - 3393},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f, err := copyFile(t, "testdata/extra.bin", "testdata/test/fault.bin")
+			if err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			f.Close()
+			defer os.Remove("testdata/test/fault.bin")
+			failWrites(t, tt.pass, tt.off)
+			goroutines := runtime.NumGoroutine()
+			stats, err := ShredWithStats("testdata/test/fault.bin", WithWriteCount(12))
+			var serr *ShredError
+			if !errors.As(err, &serr) || !errors.Is(err, errInjected) {
+				t.Fatalf("got: %v, want *ShredError\n", err)
+			}
+			if serr.Pass != tt.pass || serr.Offset != tt.offset || serr.Written != tt.written || serr.Size != 40716 {
+				t.Fatalf("unexpected error detail %+v\n", serr)
+			}
+			if stats.Passes != tt.pass {
+				t.Fatalf("expected %d passes completed, got %d\n", tt.pass, stats.Passes)
+			}
+			if _, err := os.Stat("testdata/test/fault.bin"); err != nil {
+				t.Fatalf("expected the file kept, got %v\n", err)
+			}
+			// The threads may still be returning once their result is sent.
+			n := runtime.NumGoroutine()
+			for deadline := time.Now().Add(time.Second); n > goroutines && time.Now().Before(deadline); n = runtime.NumGoroutine() {
+				time.Sleep(time.Millisecond)
+			}
+			if n > goroutines {
+				t.Fatalf("expected no goroutine left, got %d more\n", n-goroutines)
+			}
+		})
+	}
+}
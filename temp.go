@@ -0,0 +1,41 @@
+package tatter
+
+import (
+	"errors"
+	"os"
+	"sync"
+)
+
+// Creates a new temporary file in dir, as os.CreateTemp does, along with
+// a cleanup function that syncs, closes, shreds and removes it. Defer the
+// cleanup right after checking the error, so the file is destroyed even
+// on early returns:
+//
+//	f, cleanup, err := tatter.CreateSecureTemp("", "secret-*")
+//	if err != nil {
+//		return err
+//	}
+//	defer cleanup()
+//
+// The cleanup is idempotent: only the first call does the work, and any
+// later call returns the same error. Failures of every step are joined.
+// The file may be closed before the cleanup, which then only shreds it.
+func CreateSecureTemp(dir, pattern string, opts ...Option) (*os.File, func() error, error) {
+	f, err := os.CreateTemp(dir, pattern)
+	if err != nil {
+		return nil, nil, err
+	}
+	var once sync.Once
+	var cerr error
+	cleanup := func() error {
+		once.Do(func() {
+			serr, clerr := f.Sync(), f.Close()
+			if errors.Is(clerr, os.ErrClosed) { // closed by the caller
+				serr, clerr = nil, nil
+			}
+			cerr = errors.Join(serr, clerr, Shred(f.Name(), opts...))
+		})
+		return cerr
+	}
+	return f, cleanup, nil
+}
@@ -0,0 +1,67 @@
+package tatter
+
+import (
+	"os"
+	"testing"
+)
+
+func TestCreateSecureTemp(t *testing.T) {
+	f, cleanup, err := CreateSecureTemp("testdata/test", "temp-*.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if _, err := f.WriteString("Secret123"); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := cleanup(); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if _, err := os.Stat(f.Name()); err == nil {
+		t.Fatalf("file: %v, has not been removed\n", f.Name())
+	}
+	if err := cleanup(); err != nil {
+		t.Fatalf("second cleanup: %v\n", err)
+	}
+}
+
+func TestCreateSecureTempClosed(t *testing.T) {
+	f, cleanup, err := CreateSecureTemp("testdata/test", "temp-*.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if _, err := f.WriteString("Secret123"); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := f.Close(); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := cleanup(); err != nil {
+		t.Fatalf("expected a closed file shreded, got %v\n", err)
+	}
+	if _, err := os.Stat(f.Name()); err == nil {
+		t.Fatalf("file: %v, has not been removed\n", f.Name())
+	}
+}
+
+func TestCreateSecureTempRemoved(t *testing.T) {
+	f, cleanup, err := CreateSecureTemp("testdata/test", "temp-*.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := os.Remove(f.Name()); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	err = cleanup()
+	if err == nil {
+		t.Fatalf("expected shred err, got nil\n")
+	}
+	if again := cleanup(); again != err {
+		t.Fatalf("got: %v, want %v\n", again, err)
+	}
+}
+
+func TestCreateSecureTempBadDir(t *testing.T) {
+	if _, _, err := CreateSecureTemp("testdata/test/nonexistent", "temp-*"); err == nil {
+		t.Fatalf("expected err, got nil\n")
+	}
+}
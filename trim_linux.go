@@ -0,0 +1,34 @@
+//go:build linux
+
+package tatter
+
+import (
+	"math"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+const fitrim = 0xc0185879 // _IOWR('X', 121, struct fstrim_range)
+
+// Argument of FITRIM, struct fstrim_range of linux/fs.h.
+type fstrimRange struct {
+	start, len, minLen uint64
+}
+
+// Discards the free blocks of the whole filesystem holding dir, as
+// fstrim does, returning how many bytes the kernel reports discarded.
+// Needs CAP_SYS_ADMIN, and fails with EOPNOTSUPP on filesystems or
+// devices without discard support.
+func fstrim(dir string) (uint64, error) {
+	d, err := os.Open(dir)
+	if err != nil {
+		return 0, err
+	}
+	defer d.Close()
+	r := fstrimRange{len: math.MaxUint64}
+	if _, _, errno := syscall.Syscall(syscall.SYS_IOCTL, d.Fd(), fitrim, uintptr(unsafe.Pointer(&r))); errno != 0 {
+		return 0, &os.PathError{Op: "fitrim", Path: dir, Err: errno}
+	}
+	return r.len, nil
+}
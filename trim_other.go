@@ -0,0 +1,10 @@
+//go:build !linux
+
+package tatter
+
+import "errors"
+
+// FITRIM is only available on Linux.
+func fstrim(dir string) (uint64, error) {
+	return 0, errors.New("discarding free blocks is only supported on Linux")
+}
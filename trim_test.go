@@ -0,0 +1,65 @@
+package tatter
+
+import (
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+type TestWithTrimTable struct {
+	name string
+	dir  bool
+}
+
+func TestWithTrim(t *testing.T) {
+	var tests = []TestWithTrimTable{
+		{"Shred", false},
+		{"ShredDir", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.MkdirAll("testdata/test/trim", 0755); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			defer os.RemoveAll("testdata/test/trim")
+			for _, name := range []string{"a.bin", "b.bin"} {
+				f, err := copyFile(t, "testdata/small.bin", "testdata/test/trim/"+name)
+				if err != nil {
+					t.Fatalf("err: %v\n", err)
+				}
+				f.Close()
+			}
+			var mu sync.Mutex
+			var trims []string
+			logger := LoggerFunc(func(level LogLevel, msg string) {
+				mu.Lock()
+				defer mu.Unlock()
+				if strings.Contains(msg, "free blocks of the filesystem") || strings.Contains(msg, "free bytes of the filesystem") {
+					trims = append(trims, msg)
+				}
+			})
+			opts := []Option{WithTrim(true), WithLogger(logger)}
+			want := "testdata/test/trim"
+			if tt.dir {
+				if _, err := ShredDir("testdata/test/trim", opts...); err != nil {
+					t.Fatalf("err: %v\n", err)
+				}
+				want = "testdata/test"
+			} else if err := Shred("testdata/test/trim/a.bin", opts...); err != nil {
+				t.Fatalf("err: %v\n", err)
+			}
+			// Whether FITRIM is supported here or not, it is issued once
+			// and its outcome logged.
+			if len(trims) != 1 || !strings.HasSuffix(trims[0], want) && !strings.Contains(trims[0], want+":") {
+				t.Fatalf("expected one trim of %s logged, got %q\n", want, trims)
+			}
+		})
+	}
+}
+
+func TestFstrimMissingDir(t *testing.T) {
+	if _, err := fstrim("testdata/test/missing"); err == nil {
+		t.Fatalf("expected an error\n")
+	}
+}
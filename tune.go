@@ -0,0 +1,57 @@
+package tatter
+
+import (
+	"context"
+	"os"
+	"sync"
+	"time"
+)
+
+const tuneSize int64 = 4 << 20 // bytes written for every thread count tried
+
+// Thread counts tried by WithAutoTuneThreads, in order of preference on
+// ties.
+var tuneThreads = []int{1, 2, 4}
+
+// Thread counts chosen by WithAutoTuneThreads, keyed by the directory
+// they were measured in, so they are measured once per process.
+var tuned sync.Map
+
+// Returns the thread count that overwrites a temporary file in dir the
+// fastest, measuring it the first time dir is asked for.
+func autoThreads(dir string) (int, error) {
+	if n, ok := tuned.Load(dir); ok {
+		return n.(int), nil
+	}
+	f, err := os.CreateTemp(dir, ".tatter-tune-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+	overwrite := func(n int) error {
+		c := newConfig(nil)
+		c.shards = n
+		if _, _, _, err := shredPass(context.Background(), f, tuneSize, calcBuf(tuneSize), RandomSource{}, 0, c, false, nil, nil); err != nil {
+			return err
+		}
+		return f.Sync()
+	}
+	// Written once untimed, so that the first count timed does not pay
+	// for allocating the blocks the others then overwrite.
+	if err := overwrite(1); err != nil {
+		return 0, err
+	}
+	best, fastest := 0, time.Duration(0)
+	for _, n := range tuneThreads {
+		start := now()
+		if err := overwrite(n); err != nil {
+			return 0, err
+		}
+		if d := now().Sub(start); best == 0 || d < fastest {
+			best, fastest = n, d
+		}
+	}
+	n, _ := tuned.LoadOrStore(dir, best)
+	return n.(int), nil
+}
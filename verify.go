@@ -0,0 +1,63 @@
+package tatter
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// Checks that path was removed, e.g. after Shred returned: it must not
+// resolve anymore, and the listing of its parent directory must not
+// hold its name, which could otherwise be left behind by filesystems
+// caching entries. Returns ErrNotRemoved if it is still there. Whether
+// the blocks of the file were freed is up to the filesystem, and cannot
+// be checked.
+func VerifyRemoved(path string) error {
+	if _, err := os.Lstat(path); err == nil {
+		return fmt.Errorf("%w: %s still exists", ErrNotRemoved, path)
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return err
+	}
+	entries, err := os.ReadDir(filepath.Dir(path))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil // the parent is gone too
+	}
+	if err != nil {
+		return err
+	}
+	name := filepath.Base(path)
+	for _, e := range entries {
+		if e.Name() == name {
+			return fmt.Errorf("%w: %s is still listed", ErrNotRemoved, path)
+		}
+	}
+	return nil
+}
+
+// Polls the path removed by shred as WithRemovalCheck says, returning
+// ErrRemovalNotConfirmed unless it is gone at the last poll and never
+// came back once gone.
+func (c *config) confirmRemoved(path string) error {
+	delay := c.removalDelay
+	gone := false
+	for i := 0; i < c.removalPolls; i++ {
+		time.Sleep(delay)
+		delay *= 2
+		_, err := c.fsys().Lstat(path)
+		switch {
+		case errors.Is(err, fs.ErrNotExist):
+			gone = true
+		case err != nil:
+			return err
+		case gone:
+			return fmt.Errorf("%w: %s appeared again after being removed", ErrRemovalNotConfirmed, path)
+		}
+	}
+	if !gone {
+		return fmt.Errorf("%w: %s still exists after %d polls", ErrRemovalNotConfirmed, path, c.removalPolls)
+	}
+	return nil
+}
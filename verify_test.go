@@ -0,0 +1,96 @@
+package tatter
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"time"
+)
+
+type TestVerifyRemovedTable struct {
+	name string
+	path string
+	want error
+}
+
+func TestVerifyRemoved(t *testing.T) {
+	f, err := copyFile(t, "testdata/small.bin", "testdata/test/verify.bin")
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove("testdata/test/verify.bin")
+	var tests = []TestVerifyRemovedTable{
+		{"Exists", "testdata/test/verify.bin", ErrNotRemoved},
+		{"Directory", "testdata/test", ErrNotRemoved},
+		{"Missing", "testdata/test/missing.bin", nil},
+		{"MissingParent", "testdata/test/missing/verify.bin", nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := VerifyRemoved(tt.path); !errors.Is(err, tt.want) {
+				t.Fatalf("got: %v, want %v\n", err, tt.want)
+			}
+		})
+	}
+	if err := Shred("testdata/test/verify.bin"); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if err := VerifyRemoved("testdata/test/verify.bin"); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+}
+
+// FileSystem whose Lstat sees the removed file still there, or again,
+// on the polls set in exists.
+type laggingFS struct {
+	*memFS
+	removed bool
+	exists  []bool
+	polls   int
+}
+
+func (l *laggingFS) Remove(name string) error {
+	l.removed = true
+	return l.memFS.Remove(name)
+}
+
+func (l *laggingFS) Lstat(name string) (os.FileInfo, error) {
+	if l.removed && l.polls < len(l.exists) {
+		l.polls++
+		if l.exists[l.polls-1] {
+			return memInfo{name: name, size: 4}, nil
+		}
+	}
+	return l.memFS.Lstat(name)
+}
+
+type TestWithRemovalCheckTable struct {
+	name   string
+	exists []bool
+	want   error
+}
+
+func TestWithRemovalCheck(t *testing.T) {
+	var tests = []TestWithRemovalCheckTable{
+		{"Immediate", nil, nil},
+		{"Propagates", []bool{true, true}, nil},
+		{"Persists", []bool{true, true, true, true}, ErrRemovalNotConfirmed},
+		{"Reappears", []bool{false, true}, ErrRemovalNotConfirmed},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &laggingFS{memFS: newMemFS(map[string]string{"a.txt": "data"}), exists: tt.exists}
+			err := Shred("a.txt", WithFileSystem(l), WithRemovalCheck(4, time.Millisecond))
+			if !errors.Is(err, tt.want) || (tt.want == nil) != (err == nil) {
+				t.Fatalf("expected %v, got %v\n", tt.want, err)
+			}
+			if l.polls != len(tt.exists) {
+				t.Fatalf("expected %d polls seen, got %d\n", len(tt.exists), l.polls)
+			}
+		})
+	}
+	if err := Shred("a.txt", WithRemovalCheck(0, time.Second)); err == nil {
+		t.Fatalf("expected 0 polls refused\n")
+	}
+}
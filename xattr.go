@@ -0,0 +1,14 @@
+package tatter
+
+import "bytes"
+
+// Splits the NUL separated list of names returned by listxattr.
+func xattrNames(b []byte) []string {
+	var names []string
+	for _, name := range bytes.Split(b, []byte{0}) {
+		if len(name) > 0 {
+			names = append(names, string(name))
+		}
+	}
+	return names
+}
@@ -0,0 +1,65 @@
+//go:build darwin
+
+package tatter
+
+import (
+	"errors"
+	"syscall"
+	"unsafe"
+)
+
+const xattrNoFollow = 0x1
+
+func listxattr(path string, dest []byte) (int, error) {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return 0, err
+	}
+	var d unsafe.Pointer
+	if len(dest) > 0 {
+		d = unsafe.Pointer(&dest[0])
+	}
+	n, _, errno := syscall.Syscall6(syscall.SYS_LISTXATTR, uintptr(unsafe.Pointer(p)), uintptr(d), uintptr(len(dest)), xattrNoFollow, 0, 0)
+	if errno != 0 {
+		return 0, errno
+	}
+	return int(n), nil
+}
+
+func removexattr(path, name string) error {
+	p, err := syscall.BytePtrFromString(path)
+	if err != nil {
+		return err
+	}
+	n, err := syscall.BytePtrFromString(name)
+	if err != nil {
+		return err
+	}
+	_, _, errno := syscall.Syscall(syscall.SYS_REMOVEXATTR, uintptr(unsafe.Pointer(p)), uintptr(unsafe.Pointer(n)), xattrNoFollow)
+	if errno != 0 {
+		return errno
+	}
+	return nil
+}
+
+// Removes every extended attribute of the file at path, as a best
+// effort: attributes that cannot be removed, such as those protected by
+// SIP (com.apple.rootless), are left alone. Filesystems without xattrs
+// are fine.
+func scrubXattrs(path string) error {
+	size, err := listxattr(path, nil)
+	if errors.Is(err, syscall.ENOTSUP) || size == 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, size)
+	if size, err = listxattr(path, buf); err != nil {
+		return err
+	}
+	for _, name := range xattrNames(buf[:size]) {
+		removexattr(path, name)
+	}
+	return nil
+}
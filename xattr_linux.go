@@ -0,0 +1,33 @@
+//go:build linux
+
+package tatter
+
+import (
+	"errors"
+	"syscall"
+)
+
+// Overwrites with zeros and removes every extended attribute of the
+// file at path, as a best effort: attributes that cannot be changed,
+// such as SELinux labels (security.selinux) or trusted.* ones without
+// privileges, are left alone. Filesystems without xattrs are fine.
+func scrubXattrs(path string) error {
+	size, err := syscall.Listxattr(path, nil)
+	if errors.Is(err, syscall.ENOTSUP) || size == 0 {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	buf := make([]byte, size)
+	if size, err = syscall.Listxattr(path, buf); err != nil {
+		return err
+	}
+	for _, name := range xattrNames(buf[:size]) {
+		if n, err := syscall.Getxattr(path, name, nil); err == nil && n > 0 {
+			syscall.Setxattr(path, name, make([]byte, n), 0)
+		}
+		syscall.Removexattr(path, name)
+	}
+	return nil
+}
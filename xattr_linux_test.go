@@ -0,0 +1,31 @@
+//go:build linux
+
+package tatter
+
+import (
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestScrubXattrs(t *testing.T) {
+	path := "testdata/test/xattr.bin"
+	f, err := copyFile(t, "testdata/small.bin", path)
+	if err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	f.Close()
+	defer os.Remove(path)
+	if err := syscall.Setxattr(path, "user.secret", []byte("Secret123"), 0); err != nil {
+		t.Skipf("filesystem without user xattrs: %v\n", err)
+	}
+	if err := scrubXattrs(path); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+	if n, _ := syscall.Listxattr(path, make([]byte, 1024)); n != 0 {
+		t.Fatalf("xattrs left after scrubbing\n")
+	}
+	if err := Shred(path, WithScrubXattrs(true)); err != nil {
+		t.Fatalf("err: %v\n", err)
+	}
+}
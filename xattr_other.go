@@ -0,0 +1,8 @@
+//go:build !linux && !darwin
+
+package tatter
+
+// Extended attributes are only scrubbed on Linux and macOS.
+func scrubXattrs(path string) error {
+	return nil
+}
@@ -0,0 +1,13 @@
+package tatter
+
+import "testing"
+
+func TestXattrNames(t *testing.T) {
+	names := xattrNames([]byte("user.a\x00security.selinux\x00\x00"))
+	if len(names) != 2 || names[0] != "user.a" || names[1] != "security.selinux" {
+		t.Fatalf("unexpected names %q\n", names)
+	}
+	if names := xattrNames(nil); len(names) != 0 {
+		t.Fatalf("unexpected names %q\n", names)
+	}
+}